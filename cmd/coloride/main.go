@@ -1,11 +1,19 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/patrikaleksandryan/coloride/pkg/cli"
 	"github.com/patrikaleksandryan/coloride/pkg/editor"
 	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/text"
+	"github.com/patrikaleksandryan/coloride/pkg/theme"
 )
 
 const (
@@ -13,19 +21,123 @@ const (
 	windowHeight = 1150
 )
 
-func initInterface() {
+// errReported is returned if the problem has already been reported, i.e. by the flag package.
+var errReported = errors.New("reported")
+
+// initInterface opens the editor window with the files given on the command line, or with an empty text.
+// A file "-" is read from stdin, a suffix ":line" moves the cursor to the line.
+func initInterface(files []string, readOnly bool) {
 	window := editor.NewWindow()
 	gui.Append(window)
-	gui.SetFocus(window.Editor())
+	// The views need their size to scroll to the requested lines
+	gui.ResizeMainFrame(windowWidth, windowHeight)
+	e := window.Editor()
+	for _, arg := range files {
+		fname, lineNum := splitLineNum(arg)
+		var ok bool
+		if fname == "-" {
+			ok = e.LoadFromReader(os.Stdin)
+		} else {
+			ok = e.LoadFromFile(fname)
+		}
+		if !ok {
+			continue
+		}
+		if lineNum != 0 {
+			e.GoToLine(lineNum)
+		}
+		if readOnly {
+			e.SetReadOnly(true)
+		}
+	}
+	gui.SetFocus(e)
+}
+
+// splitLineNum splits "file.go:120" into the file name and the line number. The line number is 0 if there is
+// no suffix, or if a file with the whole name exists.
+func splitLineNum(arg string) (fname string, lineNum int) {
+	i := strings.LastIndexByte(arg, ':')
+	if i <= 0 {
+		return arg, 0
+	}
+	lineNum, err := strconv.Atoi(arg[i+1:])
+	if err != nil || lineNum < 1 {
+		return arg, 0
+	}
+	if _, err := os.Stat(arg); err == nil {
+		return arg, 0
+	}
+	return arg[:i], lineNum
+}
+
+// parseArgs returns the files and the flags given to the editor window.
+func parseArgs(args []string) (files []string, readOnly bool, err error) {
+	flags := flag.NewFlagSet("coloride", flag.ContinueOnError)
+	flags.BoolVar(&readOnly, "readonly", false, "open the files without allowing to edit them")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: coloride [--readonly] [file[:line] | -]...")
+		flags.PrintDefaults()
+	}
+	err = flags.Parse(args)
+	if err != nil {
+		return nil, false, err
+	}
+	return flags.Args(), readOnly, nil
+}
+
+// initDiffInterface opens the "coloride diff" window comparing two files.
+func initDiffInterface(fnameA, fnameB string) error {
+	err := theme.LoadFor(filepath.Dir(fnameA))
+	if err != nil {
+		return fmt.Errorf("load configuration: %w", err)
+	}
+	a := text.NewText()
+	err = a.LoadFromFile(fnameA)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", fnameA, err)
+	}
+	b := text.NewText()
+	err = b.LoadFromFile(fnameB)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", fnameB, err)
+	}
+	window := editor.NewDiffWindow(a.(*text.TextImpl), b.(*text.TextImpl), fnameA, fnameB)
+	gui.Append(window)
+	gui.SetFocus(window.View())
+	return nil
 }
 
-func run() error {
+func run(args []string) error {
+	isDiff := len(args) != 0 && args[0] == "diff"
+	if isDiff && len(args) != 3 {
+		return errors.New("usage: coloride diff old-file new-file")
+	}
+	var files []string
+	var readOnly bool
+	if !isDiff {
+		var err error
+		files, readOnly, err = parseArgs(args)
+		if err == flag.ErrHelp {
+			return nil
+		} else if err != nil {
+			return errReported
+		}
+	}
+
 	err := gui.Init(windowWidth, windowHeight)
 	if err != nil {
 		return err
 	}
 
-	initInterface()
+	if isDiff {
+		err = initDiffInterface(args[1], args[2])
+		if err != nil {
+			gui.Close()
+			return err
+		}
+	} else {
+		initInterface(files, readOnly)
+	}
 
 	err = gui.Run()
 	if err != nil {
@@ -38,6 +150,11 @@ func run() error {
 }
 
 func main() {
+	// Command-line commands write to stdout, so they are run before the banner is printed
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	fmt.Println(`
 		 ▗▄▄▖ ▗▄▖ ▗▖    ▗▄▖ ▗▄▄▖     ▗▄▄▄▖▗▄▄▄  ▗▄▄▄▖
 		▐▌   ▐▌ ▐▌▐▌   ▐▌ ▐▌▐▌ ▐▌      █  ▐▌  █ ▐▌   
@@ -45,8 +162,10 @@ func main() {
 		▝▚▄▄▖▝▚▄▞▘▐▙▄▄▖▝▚▄▞▘▐▌ ▐▌    ▗▄█▄▖▐▙▄▄▀ ▐▙▄▄▖
 	`)
 
-	if err := run(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	if err := run(os.Args[1:]); err != nil {
+		if err != errReported {
+			fmt.Fprintln(os.Stderr, err)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,176 @@
+// Package atomicfile replaces files so that a crash or a full disk while saving never leaves them half-written.
+package atomicfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// BackupSuffix is appended to the name of a file to get the name of its backup.
+const BackupSuffix = "~"
+
+const maxLinks = 40 // Number of symbolic links followed, like Linux does
+
+// WriteFile replaces the contents of the file fname with the data written by write. The data is written to
+// a temporary file in the same directory, which is synced and renamed over the file only if everything
+// has succeeded. If fname is a symbolic link, the file it points to is replaced. The file keeps its mode;
+// a new file is created with the usual permissions.
+// If backup is set, the old contents are kept in a file with BackupSuffix.
+func WriteFile(fname string, backup bool, write func(w io.Writer) error) (err error) {
+	target := resolveLinks(fname)
+	mode, existed, err := fileMode(target)
+	if err != nil {
+		return err
+	}
+	if !existed {
+		// fileMode has created an empty file to learn the permissions, it must not be left behind
+		defer func() {
+			if err != nil {
+				os.Remove(target)
+			}
+		}()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	tmp := f.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	err = writeTemp(f, write, mode)
+	if err != nil {
+		return err
+	}
+	if backup && existed {
+		err = makeBackup(target)
+		if err != nil {
+			return fmt.Errorf("make backup: %w", err)
+		}
+	}
+	err = os.Rename(tmp, target)
+	if err != nil {
+		return fmt.Errorf("replace file: %w", err)
+	}
+	syncDir(filepath.Dir(target))
+	return nil
+}
+
+// resolveLinks returns the name of the file that fname refers to. A symbolic link to a file that does not
+// exist yet is followed too, so that the file is created and the link is kept.
+func resolveLinks(fname string) string {
+	if resolved, err := filepath.EvalSymlinks(fname); err == nil {
+		return resolved
+	}
+	for i := 0; i < maxLinks; i++ {
+		link, err := os.Readlink(fname)
+		if err != nil {
+			return fname
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(fname), link)
+		}
+		fname = link
+	}
+	return fname
+}
+
+// fileMode returns the permissions of the file fname. If it does not exist, an empty file is created with
+// the permissions of new files, which depend on the umask.
+func fileMode(fname string) (mode fs.FileMode, existed bool, err error) {
+	info, err := os.Stat(fname)
+	if err == nil {
+		return info.Mode().Perm(), true, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return 0, false, fmt.Errorf("stat file: %w", err)
+	}
+	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return 0, false, fmt.Errorf("create file: %w", err)
+	}
+	info, err = f.Stat()
+	f.Close()
+	if err != nil {
+		os.Remove(fname)
+		return 0, false, fmt.Errorf("stat file: %w", err)
+	}
+	return info.Mode().Perm(), false, nil
+}
+
+// writeTemp writes the data to the temporary file f, syncs it to the disk and closes it.
+func writeTemp(f *os.File, write func(w io.Writer) error, mode fs.FileMode) error {
+	err := write(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("write file: %w", err)
+	}
+	err = f.Chmod(mode)
+	if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		f.Close()
+		return fmt.Errorf("set file mode: %w", err)
+	}
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("sync file: %w", err)
+	}
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("close file: %w", err)
+	}
+	return nil
+}
+
+// makeBackup keeps the current contents of fname in the backup file. The backup is a hard link if possible,
+// so that the file is not copied.
+func makeBackup(fname string) error {
+	backup := fname + BackupSuffix
+	err := os.Remove(backup)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if os.Link(fname, backup) == nil {
+		return nil
+	}
+	return copyFile(fname, backup)
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// syncDir makes the rename in dir durable. It is not possible on all systems, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,212 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+var errWrite = errors.New("disk full")
+
+// writeString returns a write function for WriteFile that writes s.
+func writeString(s string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+// failingWrite writes a part of the data and fails, as on a full disk.
+func failingWrite(w io.Writer) error {
+	_, err := io.WriteString(w, "partial")
+	if err != nil {
+		return err
+	}
+	return errWrite
+}
+
+// checkFile fails the test if the file fname does not contain want.
+func checkFile(t *testing.T, fname, want string) {
+	t.Helper()
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s contains %q, want %q", filepath.Base(fname), data, want)
+	}
+}
+
+// checkNoTemp fails the test if a temporary file has been left in dir.
+func checkNoTemp(t *testing.T, dir string) {
+	t.Helper()
+	tmps, err := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmps) != 0 {
+		t.Errorf("temporary files are left: %v", tmps)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "a.go")
+	err := os.WriteFile(fname, []byte("old"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteFile(fname, false, writeString("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, fname, "new")
+	checkNoTemp(t, dir)
+	if _, err := os.Stat(fname + BackupSuffix); err == nil {
+		t.Error("a backup is made without being asked for")
+	}
+}
+
+func TestWriteErrorKeepsFile(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "a.go")
+	err := os.WriteFile(fname, []byte("old\r\ncontents\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteFile(fname, true, failingWrite)
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("error is %v, want %v", err, errWrite)
+	}
+	checkFile(t, fname, "old\r\ncontents\n")
+	checkNoTemp(t, dir)
+	if _, err := os.Stat(fname + BackupSuffix); err == nil {
+		t.Error("a backup is made although the file is not replaced")
+	}
+}
+
+func TestWriteErrorRemovesNewFile(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "a.go")
+	err := WriteFile(fname, false, failingWrite)
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("error is %v, want %v", err, errWrite)
+	}
+	if _, err := os.Lstat(fname); !errors.Is(err, os.ErrNotExist) {
+		t.Error("the new file is left after an error:", err)
+	}
+	checkNoTemp(t, dir)
+}
+
+func TestWriteFileKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported")
+	}
+	dir := t.TempDir()
+	for _, mode := range []os.FileMode{0600, 0755, 0640} {
+		fname := filepath.Join(dir, "a.sh")
+		err := os.WriteFile(fname, []byte("old"), mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chmod(fname, mode) // Not affected by the umask
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = WriteFile(fname, false, writeString("new"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("mode is %v, want %v", info.Mode().Perm(), mode)
+		}
+	}
+}
+
+func TestWriteFileKeepsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.go")
+	link := filepath.Join(dir, "link.go")
+	err := os.WriteFile(target, []byte("old"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("target.go", link); err != nil {
+		t.Skip("symbolic links are not supported:", err)
+	}
+	err = WriteFile(link, true, writeString("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Error("the link is replaced with a file")
+	}
+	checkFile(t, target, "new")
+	checkFile(t, target+BackupSuffix, "old")
+	checkNoTemp(t, dir)
+}
+
+func TestWriteFileThroughDanglingSymlink(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(dir, "link.go")
+	if err := os.Symlink(filepath.Join("sub", "target.go"), link); err != nil {
+		t.Skip("symbolic links are not supported:", err)
+	}
+	err := os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteFile(link, false, writeString("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Error("the link is replaced with a file")
+	}
+	checkFile(t, filepath.Join(dir, "sub", "target.go"), "new")
+}
+
+func TestWriteFileBackup(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "a.go")
+	err := os.WriteFile(fname, []byte("first"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteFile(fname, true, writeString("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, fname+BackupSuffix, "first")
+	err = WriteFile(fname, true, writeString("third"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, fname, "third")
+	checkFile(t, fname+BackupSuffix, "second")
+
+	// A new file has no backup
+	newName := filepath.Join(dir, "b.go")
+	err = WriteFile(newName, true, writeString("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(newName + BackupSuffix); err == nil {
+		t.Error("a backup of a new file is made")
+	}
+}
@@ -0,0 +1,132 @@
+// Package charset detects the encoding of a file and converts its contents to and from UTF-8,
+// so that a file is saved in the same encoding it was loaded in.
+package charset
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type Encoding int
+
+const (
+	// Encodings
+
+	UTF8    Encoding = iota // UTF-8 without a byte order mark
+	UTF8BOM                 // UTF-8 starting with the byte order mark EF BB BF
+	UTF16LE                 // Little-endian UTF-16 starting with the byte order mark FF FE
+	UTF16BE                 // Big-endian UTF-16 starting with the byte order mark FE FF
+)
+
+// Bytes that are not valid UTF-8 are kept in texts as the runes from rawBytes+0x80 to rawBytes+0xFF, and
+// written back as the same bytes. These runes are low surrogates, which valid UTF-8 never decodes to.
+const rawBytes = 0xDC00
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case UTF8:
+		return "UTF-8"
+	case UTF8BOM:
+		return "UTF-8 BOM"
+	case UTF16LE:
+		return "UTF-16 LE"
+	case UTF16BE:
+		return "UTF-16 BE"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+}
+
+// Detect returns the encoding of data. UTF-16 is only recognized by its byte order mark.
+// Data that is not valid UTF-8 is still taken as UTF-8, see RawByte.
+func Detect(data []byte) Encoding {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return UTF8BOM
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return UTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return UTF16BE
+	}
+	return UTF8
+}
+
+// RawByte returns the rune that stands for the byte b, read where b is not valid UTF-8.
+func RawByte(b byte) rune {
+	return rawBytes + rune(b)
+}
+
+// RawByteOf returns the byte that r stands for, if r has been returned by RawByte.
+func RawByteOf(r rune) (byte, bool) {
+	if rawBytes+0x80 <= r && r <= rawBytes+0xFF {
+		return byte(r - rawBytes), true
+	}
+	return 0, false
+}
+
+// Decode converts data in the encoding e to UTF-8. The byte order mark is removed.
+func Decode(data []byte, e Encoding) []byte {
+	switch e {
+	case UTF8BOM:
+		return bytes.TrimPrefix(data, bomUTF8)
+	case UTF16LE:
+		return decodeUTF16(bytes.TrimPrefix(data, bomUTF16LE), false)
+	case UTF16BE:
+		return decodeUTF16(bytes.TrimPrefix(data, bomUTF16BE), true)
+	}
+	return data
+}
+
+// decodeUTF16 converts UTF-16 data without the byte order mark to UTF-8. An odd last byte is lost.
+func decodeUTF16(data []byte, bigEndian bool) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	res := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		res = utf8.AppendRune(res, r)
+	}
+	return res
+}
+
+// Encode converts UTF-8 data to the encoding e and adds its byte order mark.
+func Encode(data []byte, e Encoding) []byte {
+	switch e {
+	case UTF8BOM:
+		return append(append([]byte{}, bomUTF8...), data...)
+	case UTF16LE, UTF16BE:
+		return encodeUTF16(data, e == UTF16BE)
+	}
+	return data
+}
+
+func encodeUTF16(data []byte, bigEndian bool) []byte {
+	units := utf16.Encode([]rune(string(data)))
+	res := make([]byte, 0, 2*len(units)+2)
+	if bigEndian {
+		res = append(res, bomUTF16BE...)
+	} else {
+		res = append(res, bomUTF16LE...)
+	}
+	for _, u := range units {
+		if bigEndian {
+			res = append(res, byte(u>>8), byte(u))
+		} else {
+			res = append(res, byte(u), byte(u>>8))
+		}
+	}
+	return res
+}
@@ -0,0 +1,305 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/patrikaleksandryan/coloride/pkg/atomicfile"
+	"github.com/patrikaleksandryan/coloride/pkg/charset"
+	"github.com/patrikaleksandryan/coloride/pkg/syntax"
+	"github.com/patrikaleksandryan/coloride/pkg/text"
+	"github.com/patrikaleksandryan/coloride/pkg/theme"
+)
+
+// Command is a command-line mode of ColorIDE that does not open a window.
+type Command struct {
+	Name  string
+	Usage string
+	Run   func(c *Context, args []string) error
+}
+
+// Context holds the standard streams of a running command.
+type Context struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Flags  *flag.FlagSet
+}
+
+// errFailed is returned by a command that has already reported the problem and needs exit code 1.
+var errFailed = errors.New("failed")
+
+var commands []*Command
+
+func init() {
+	commands = []*Command{
+		{Name: "strip", Usage: "strip [-o output] [file]", Run: runStrip},
+		{Name: "apply", Usage: "apply [-o output] colored-file [file]", Run: runApply},
+		{Name: "check", Usage: "check [file...]", Run: runCheck},
+		{Name: "merge-driver", Usage: "merge-driver base current other [path]", Run: runMergeDriver},
+	}
+}
+
+func findCommand(name string) *Command {
+	for _, c := range commands {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// IsCommand reports whether name is a name of a command-line command.
+func IsCommand(name string) bool {
+	return findCommand(name) != nil
+}
+
+// Run runs the command args[0] with arguments args[1:] and returns the exit code of the process.
+// A file named "-" or a missing file argument means stdin or stdout.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(stderr, "coloride: unknown command %q\n", args[0])
+		return 2
+	}
+	c := &Context{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+		Flags:  flag.NewFlagSet(cmd.Name, flag.ContinueOnError),
+	}
+	c.Flags.SetOutput(stderr)
+	c.Flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: coloride %s\n", cmd.Usage)
+		c.Flags.PrintDefaults()
+	}
+
+	err := cmd.Run(c, args[1:])
+	if err == flag.ErrHelp {
+		return 0
+	} else if err == errFailed {
+		return 1
+	} else if err != nil {
+		fmt.Fprintf(stderr, "coloride %s: %v\n", cmd.Name, err)
+		return 1
+	}
+	return 0
+}
+
+// NewText returns an empty text that is not displayed.
+func NewText() *text.TextImpl {
+	return text.NewText().(*text.TextImpl)
+}
+
+// readInput returns the contents of the file fname or of stdin.
+func (c *Context) readInput(fname string) ([]byte, error) {
+	if fname == "" || fname == "-" {
+		return io.ReadAll(c.Stdin)
+	}
+	return os.ReadFile(fname)
+}
+
+// loadText reads the file fname or stdin into a new text.
+func (c *Context) loadText(fname string) (*text.TextImpl, []byte, error) {
+	return c.loadTextAs(fname, fname)
+}
+
+// loadTextAs reads the file fname or stdin into a new text, using the color comments of the language of path.
+func (c *Context) loadTextAs(fname, path string) (*text.TextImpl, []byte, error) {
+	data, err := c.readInput(fname)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read input: %w", err)
+	}
+	dir := "."
+	if path != "" && path != "-" {
+		dir = filepath.Dir(path)
+	}
+	err = theme.LoadFor(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load configuration: %w", err)
+	}
+	t := NewText()
+	first, _, _ := bytes.Cut(charset.Decode(data, charset.Detect(data)), []byte("\n"))
+	t.SetColorComment(syntax.ColorCommentFor(path, string(bytes.TrimSuffix(first, []byte("\r")))))
+	err = t.Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("load %s: %w", displayName(fname), err)
+	}
+	return t, data, nil
+}
+
+// writeOutput writes data to the file fname or to stdout.
+func (c *Context) writeOutput(fname string, data []byte) error {
+	if fname == "" || fname == "-" {
+		_, err := c.Stdout.Write(data)
+		return err
+	}
+	return atomicfile.WriteFile(fname, false, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+func displayName(fname string) string {
+	if fname == "" || fname == "-" {
+		return "<stdin>"
+	}
+	return fname
+}
+
+// runStrip writes the file without color comments.
+func runStrip(c *Context, args []string) error {
+	output := c.Flags.String("o", "", "output file (default stdout)")
+	err := c.Flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if c.Flags.NArg() > 1 {
+		c.Flags.Usage()
+		return errFailed
+	}
+
+	t, _, err := c.loadText(c.Flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = t.WritePlain(&buf)
+	if err != nil {
+		return err
+	}
+	return c.writeOutput(*output, buf.Bytes())
+}
+
+// runApply copies the color markup of colored-file onto the other revision of the same file.
+func runApply(c *Context, args []string) error {
+	output := c.Flags.String("o", "", "output file (default stdout)")
+	err := c.Flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if c.Flags.NArg() < 1 || c.Flags.NArg() > 2 {
+		c.Flags.Usage()
+		return errFailed
+	}
+
+	src, _, err := c.loadText(c.Flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	t, _, err := c.loadText(c.Flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	t.CopyColorsFrom(src)
+
+	var buf bytes.Buffer
+	err = t.Write(&buf)
+	if err != nil {
+		return err
+	}
+	return c.writeOutput(*output, buf.Bytes())
+}
+
+// runCheck reports files that would change if they were opened and saved.
+func runCheck(c *Context, args []string) error {
+	err := c.Flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	fnames := c.Flags.Args()
+	if len(fnames) == 0 {
+		fnames = []string{"-"}
+	}
+
+	failed := false
+	for _, fname := range fnames {
+		t, data, err := c.loadText(fname)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		err = t.Write(&buf)
+		if err != nil {
+			return err
+		}
+		if lineNum := firstDifference(data, buf.Bytes()); lineNum != 0 {
+			fmt.Fprintf(c.Stderr, "%s:%d: does not round-trip\n", displayName(fname), lineNum)
+			failed = true
+		}
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+// runMergeDriver merges the changes from other into current, as a git merge driver invoked with %O %A %B %P.
+// The result is written to current. The optional path is the name of the merged file in the repository,
+// which selects the color comments of its language. Conflicts are reported with exit code 1.
+func runMergeDriver(c *Context, args []string) error {
+	err := c.Flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if c.Flags.NArg() != 3 && c.Flags.NArg() != 4 {
+		c.Flags.Usage()
+		return errFailed
+	}
+	baseName, currentName, otherName := c.Flags.Arg(0), c.Flags.Arg(1), c.Flags.Arg(2)
+	path := currentName
+	if c.Flags.NArg() == 4 {
+		path = c.Flags.Arg(3)
+	}
+
+	base, _, err := c.loadTextAs(baseName, path)
+	if err != nil {
+		return err
+	}
+	current, _, err := c.loadTextAs(currentName, path)
+	if err != nil {
+		return err
+	}
+	other, _, err := c.loadTextAs(otherName, path)
+	if err != nil {
+		return err
+	}
+	t, conflicts := text.Merge(base, current, other, "ours", "theirs")
+
+	var buf bytes.Buffer
+	err = t.Write(&buf)
+	if err != nil {
+		return err
+	}
+	err = c.writeOutput(currentName, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if conflicts != 0 {
+		fmt.Fprintf(c.Stderr, "%s: %d conflicts\n", currentName, conflicts)
+		return errFailed
+	}
+	return nil
+}
+
+// firstDifference returns the 1-based number of the first line where a and b differ, or 0 if they are equal.
+func firstDifference(a, b []byte) int {
+	lineNum := 1
+	for i := 0; i != len(a) && i != len(b); i++ {
+		if a[i] != b[i] {
+			return lineNum
+		}
+		if a[i] == '\n' {
+			lineNum++
+		}
+	}
+	if len(a) != len(b) {
+		return lineNum
+	}
+	return 0
+}
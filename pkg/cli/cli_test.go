@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// run runs the command with stdin and returns its exit code and stdout.
+func run(t *testing.T, stdin string, args ...string) (int, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(args, strings.NewReader(stdin), &stdout, &stderr)
+	if code != 0 {
+		t.Logf("coloride %s: %s", strings.Join(args, " "), stderr.String())
+	}
+	return code, stdout.String()
+}
+
+// writeFile writes a file into dir and returns its name.
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	fname := filepath.Join(dir, name)
+	err := os.WriteFile(fname, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fname
+}
+
+func TestStripAndApply(t *testing.T) {
+	tests := []struct {
+		name, src, plain string
+	}{
+		{"a.go", "x := 1 ///5 1R\ny := 2\n", "x := 1\ny := 2\n"},
+		{"a.go", "x := 1\t\t///5 1R\r\ny := 2\r\n", "x := 1\r\ny := 2\r\n"},
+		{"a.go", "\xef\xbb\xbfx := 1 ///1g\nz\n", "\xef\xbb\xbfx := 1\nz\n"},
+		{"a.go", "x := 1 ///1{todo}\n", "x := 1\n"},
+		{"a.py", "x = 1 ###4 1R\n", "x = 1\n"},
+		{"a.go", "plain\n", "plain\n"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		colored := writeFile(t, dir, tt.name, tt.src)
+		code, plain := run(t, "", "strip", colored)
+		if code != 0 || plain != tt.plain {
+			t.Errorf("%q: stripped into %q, exit code %d, want %q", tt.src, plain, code, tt.plain)
+		}
+		stripped := writeFile(t, dir, "stripped"+filepath.Ext(tt.name), plain)
+		code, got := run(t, "", "apply", colored, stripped)
+		if code != 0 || got != tt.src {
+			t.Errorf("%q: applied to the stripped file gives %q, exit code %d", tt.src, got, code)
+		}
+	}
+}
+
+func TestApplyToRevision(t *testing.T) {
+	dir := t.TempDir()
+	colored := writeFile(t, dir, "old.go", "a := 1 ///5 1R\nb := 2\nc := 3 ///1g\n")
+	// stdin is the new revision
+	code, got := run(t, "x := 0\na := 1\nnew()\nb := 2\nc := 30\n", "apply", colored)
+	want := "x := 0\na := 1 ///5 1R\nnew()\nb := 2\nc := 30 ///1g\n"
+	if code != 0 || got != want {
+		t.Errorf("applied as %q, exit code %d, want %q", got, code, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		src  string
+		code int
+	}{
+		{"x := 1 ///5 1R\n", 0},
+		{"x := 1\t\t///1R\r\n", 0},
+		{"plain\n", 0},
+		{"ab ///5 2R\n", 1},      // The run is beyond the end of the line, left after an edit by another editor
+		{"ab ///1 1R 0 1g\n", 1}, // Not written this way
+	}
+	for _, tt := range tests {
+		if code, _ := run(t, tt.src, "check"); code != tt.code {
+			t.Errorf("%q: exit code %d, want %d", tt.src, code, tt.code)
+		}
+	}
+
+	dir := t.TempDir()
+	good := writeFile(t, dir, "good.go", "x ///1R\n")
+	bad := writeFile(t, dir, "bad.go", "x\ny ///9R\n")
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"check", good, bad}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	if want := bad + ":2: does not round-trip\n"; stderr.String() != want {
+		t.Errorf("reported %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestMergeDriver(t *testing.T) {
+	tests := []struct {
+		name                 string
+		base, current, other string
+		want                 string
+		code                 int
+	}{
+		{"clean", "a\nb\n", "a ///1R\nb\n", "a\nb ///1g\n", "a ///1R\nb ///1g\n", 0},
+		{"conflict", "a\nb\n", "a ///1R\nb\n", "a ///1g\nb\n",
+			"<<<<<<< ours\na ///1R\n=======\na ///1g\n>>>>>>> theirs\nb\n", 1},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		base := writeFile(t, dir, "base", tt.base)
+		current := writeFile(t, dir, "current", tt.current)
+		other := writeFile(t, dir, "other", tt.other)
+		code, _ := run(t, "", "merge-driver", base, current, other, "pkg/a.go")
+		if code != tt.code {
+			t.Errorf("%s: exit code %d, want %d", tt.name, code, tt.code)
+		}
+		data, err := os.ReadFile(current)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s: merged into %q, want %q", tt.name, data, tt.want)
+		}
+	}
+}
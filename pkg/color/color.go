@@ -1,10 +1,9 @@
 package color
 
-import (
-	"github.com/veandco/go-sdl2/sdl"
-)
-
-type Color sdl.Color
+// Color has the same layout as sdl.Color, so it can be converted to it in the GUI.
+type Color struct {
+	R, G, B, A uint8
+}
 
 var (
 	Transparent = Color{R: 0, G: 0, B: 0, A: 0}
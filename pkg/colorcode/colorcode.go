@@ -1,11 +1,19 @@
 package colorcode
 
+import (
+	"fmt"
+
+	"github.com/patrikaleksandryan/coloride/pkg/palette"
+)
+
 const (
 	// Symbols
 
 	Number         = iota // i.e. "12"
 	Letter                // i.e. "R"
 	NumberedLetter        // i.e. "12R"
+	Name                  // i.e. "{todo}"
+	NumberedName          // i.e. "12{todo}"
 	EOC                   // End of code
 )
 
@@ -13,6 +21,7 @@ type Scanner struct {
 	Sym    int // One of symbol constants
 	Number int
 	Letter rune
+	Name   string
 
 	code []rune
 	ch   rune // invariant: ch = code[0], or 0
@@ -38,6 +47,10 @@ func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isNameChar(ch rune) bool {
+	return isLetter(ch) || isDigit(ch) || ch == '_' || ch == '-' || ch == '.'
+}
+
 func (s *Scanner) Scan() {
 	s.skipWhitespace()
 	if len(s.code) == 0 {
@@ -58,6 +71,12 @@ func (s *Scanner) Scan() {
 				s.Letter = s.ch
 				s.read()
 				s.Sym = NumberedLetter
+			} else if s.ch == '{' {
+				if s.scanName() {
+					s.Sym = NumberedName
+				} else {
+					s.Sym = EOC
+				}
 			} else {
 				s.Sym = Number
 			}
@@ -65,12 +84,35 @@ func (s *Scanner) Scan() {
 			s.Sym = Letter
 			s.Letter = s.ch
 			s.read()
+		} else if s.ch == '{' {
+			if s.scanName() {
+				s.Sym = Name
+			} else {
+				s.Sym = EOC
+			}
 		} else { // Undefined character
 			s.Sym = EOC
 		}
 	}
 }
 
+// scanName reads a color name in braces into s.Name. Reports false if the name is empty or malformed.
+func (s *Scanner) scanName() bool {
+	s.read() // Skip '{'
+	start := s.code
+	n := 0
+	for isNameChar(s.ch) {
+		n++
+		s.read()
+	}
+	if s.ch != '}' || n == 0 {
+		return false
+	}
+	s.Name = string(start[:n])
+	s.read() // Skip '}'
+	return true
+}
+
 // read removes first character from s.code.
 func (s *Scanner) read() {
 	if len(s.code) != 0 {
@@ -91,51 +133,24 @@ func (s *Scanner) skipWhitespace() {
 	}
 }
 
-// Color returns s.Letter as a color number.
+// Color returns the color number of s.Letter or s.Name, depending on s.Sym.
+// Unknown letters give color 0, unknown names are added to the palette.
 func (s *Scanner) Color() int {
-	switch s.Letter {
-	case 'r':
-		return 1
-	case 'g':
-		return 2
-	case 'b':
-		return 3
-	case 'y':
-		return 4
-	case 'R':
-		return 5
-	case 'G':
-		return 6
-	case 'B':
-		return 7
-	case 'Y':
-		return 8
-	default:
-		return 0
+	if s.Sym == Name || s.Sym == NumberedName {
+		return palette.Resolve(s.Name)
 	}
+	return palette.ByLetter(s.Letter)
 }
 
-// ToLetter returns the letter of the given color number.
-// color must not be 0.
-func ToLetter(color int) rune {
-	switch color {
-	case 1:
-		return 'r'
-	case 2:
-		return 'g'
-	case 3:
-		return 'b'
-	case 4:
-		return 'y'
-	case 5:
-		return 'R'
-	case 6:
-		return 'G'
-	case 7:
-		return 'B'
-	case 8:
-		return 'Y'
-	default:
+// Code returns the color as it is written in a color code: its letter, or its name in braces
+// if the color has no letter. color must not be 0.
+func Code(color int) string {
+	if color <= 0 || color >= palette.Count() {
 		panic("impossible: color number")
 	}
+	e := palette.Get(color)
+	if e.Letter != 0 {
+		return string(e.Letter)
+	}
+	return fmt.Sprintf("{%s}", e.Name)
 }
@@ -0,0 +1,126 @@
+package diff
+
+// Match is a pair of equal elements of two compared sequences: a[A] = b[B].
+type Match struct {
+	A, B int
+}
+
+type differ struct {
+	equal   func(i, j int) bool
+	matches []Match
+	vf, vb  []int // Furthest reaching x-coordinates of forward and backward paths, indexed by diagonal
+}
+
+// Compare returns the longest common subsequence of two sequences of lengths n and m, as a list of matches
+// sorted in increasing order. equal reports whether a[i] = b[j]. It uses the linear space variation of
+// the Myers' O(ND) difference algorithm.
+func Compare(n, m int, equal func(i, j int) bool) []Match {
+	size := n + m + 3
+	d := &differ{
+		equal: equal,
+		vf:    make([]int, size),
+		vb:    make([]int, size),
+	}
+	d.compare(0, n, 0, m)
+	return d.matches
+}
+
+// Strings compares two sequences of strings, i.e. lines of two files.
+func Strings(a, b []string) []Match {
+	return Compare(len(a), len(b), func(i, j int) bool {
+		return a[i] == b[j]
+	})
+}
+
+// Runes compares two sequences of characters.
+func Runes(a, b []rune) []Match {
+	return Compare(len(a), len(b), func(i, j int) bool {
+		return a[i] == b[j]
+	})
+}
+
+// compare appends the matches of ranges a[aLo; aHi) and b[bLo; bHi) to d.matches.
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	// Common prefix
+	for aLo < aHi && bLo < bHi && d.equal(aLo, bLo) {
+		d.matches = append(d.matches, Match{A: aLo, B: bLo})
+		aLo++
+		bLo++
+	}
+	// Common suffix, appended after the middle part
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.equal(aHi-suffix-1, bHi-suffix-1) {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	if aLo != aHi && bLo != bHi {
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		for ; x != u; x, y = x+1, y+1 {
+			d.matches = append(d.matches, Match{A: x, B: y})
+		}
+		d.compare(u, aHi, v, bHi)
+	}
+
+	for i := 0; i != suffix; i++ {
+		d.matches = append(d.matches, Match{A: aHi + i, B: bHi + i})
+	}
+}
+
+// middleSnake finds the middle snake of the shortest edit path for ranges a[aLo; aHi) and b[bLo; bHi),
+// which must be non-empty and must not have a common prefix or suffix. The snake goes from (x; y) to (u; v).
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	offset := max + 1 // Index of diagonal 0 in vf and vb
+	vf, vb := d.vf, d.vb
+	vf[offset+1] = 0
+	vb[offset+1] = 0
+
+	for D := 0; D <= max; D++ {
+		// Forward paths, diagonal k = x - y
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || k != D && vf[offset+k-1] < vf[offset+k+1] {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.equal(aLo+x, bLo+y) {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+			// Diagonal k of the forward path is diagonal (delta - k) of the backward path
+			if odd && delta-k >= -(D-1) && delta-k <= D-1 && x+vb[offset+delta-k] >= n {
+				return aLo + x0, bLo + y0, aLo + x, bLo + y
+			}
+		}
+		// Backward paths, x and y are measured from the ends of the ranges
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || k != D && vb[offset+k-1] < vb[offset+k+1] {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.equal(aHi-x-1, bHi-y-1) {
+				x++
+				y++
+			}
+			vb[offset+k] = x
+			if !odd && delta-k >= -D && delta-k <= D && x+vf[offset+delta-k] >= n {
+				return aHi - x, bHi - y, aHi - x0, bHi - y0
+			}
+		}
+	}
+	panic("impossible: middle snake not found")
+}
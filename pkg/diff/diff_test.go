@@ -0,0 +1,116 @@
+package diff
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// lcsLength returns the length of the longest common subsequence of a and b by dynamic programming.
+func lcsLength(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// checkMatches fails the test if matches is not a common subsequence of a and b of the length of the longest one.
+func checkMatches(t *testing.T, a, b []string, matches []Match) {
+	t.Helper()
+	for k, m := range matches {
+		if m.A < 0 || m.A >= len(a) || m.B < 0 || m.B >= len(b) || a[m.A] != b[m.B] {
+			t.Fatalf("%q, %q: match %v is wrong", a, b, m)
+		}
+		if k != 0 && (m.A <= matches[k-1].A || m.B <= matches[k-1].B) {
+			t.Fatalf("%q, %q: matches %v and %v are not increasing", a, b, matches[k-1], m)
+		}
+	}
+	if want := lcsLength(a, b); len(matches) != want {
+		t.Fatalf("%q, %q: %d matches, want %d", a, b, len(matches), want)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"", ""},
+		{"", "abc"},
+		{"abc", ""},
+		{"abc", "abc"},
+		{"abc", "xyz"},
+		{"abcabba", "cbabac"},
+		{"a", "a"},
+		{"ab", "ba"},
+		{"xaby", "ab"},
+		{"ab", "xaby"},
+	}
+	for _, tt := range tests {
+		a, b := strings.Split(tt.a, ""), strings.Split(tt.b, "")
+		checkMatches(t, a, b, Strings(a, b))
+	}
+
+	// Both sides identical match completely, all lines different do not match at all
+	lines := []string{"a", "b", "c", "d"}
+	if got := Strings(lines, lines); len(got) != len(lines) {
+		t.Errorf("identical sequences give %d matches", len(got))
+	}
+	if got := Strings(lines, []string{"w", "x", "y", "z"}); len(got) != 0 {
+		t.Errorf("different sequences give %d matches", len(got))
+	}
+}
+
+func TestStringsRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	randomLines := func(n, alphabet int) []string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = string(rune('a' + r.IntN(alphabet)))
+		}
+		return lines
+	}
+	for i := 0; i < 2000; i++ {
+		alphabet := 1 + r.IntN(6)
+		a := randomLines(r.IntN(40), alphabet)
+		var b []string
+		if r.IntN(2) == 0 {
+			b = randomLines(r.IntN(40), alphabet)
+		} else {
+			// An edited revision of a, as diffs usually see
+			for _, line := range a {
+				switch r.IntN(6) {
+				case 0:
+				case 1:
+					b = append(b, line, randomLines(1, alphabet)[0])
+				case 2:
+					b = append(b, randomLines(1, alphabet)[0])
+				default:
+					b = append(b, line)
+				}
+			}
+		}
+		checkMatches(t, a, b, Strings(a, b))
+	}
+}
+
+func TestRunes(t *testing.T) {
+	a, b := []rune("größe"), []rune("grosse")
+	matches := Runes(a, b)
+	want := []Match{{0, 0}, {1, 1}, {4, 5}}
+	if len(matches) != len(want) {
+		t.Fatalf("matches are %v, want %v", matches, want)
+	}
+	for i := range want {
+		if matches[i] != want[i] {
+			t.Fatalf("matches are %v, want %v", matches, want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package editor
+
+import (
+	"fmt"
+
+	"github.com/patrikaleksandryan/coloride/pkg/color"
+	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/text"
+	"github.com/patrikaleksandryan/coloride/pkg/theme"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+var (
+	diffRemovedBgColor = color.MakeColor(70, 25, 25)
+	diffAddedBgColor   = color.MakeColor(25, 65, 25)
+	diffFillerColor    = color.MakeColor(30, 30, 30)
+	diffColorFlagColor = color.MakeColor(170, 60, 200)
+	diffTextFlagColor  = color.MakeColor(210, 150, 50)
+)
+
+// diffSide is one of the two compared texts.
+type diffSide struct {
+	text   *text.TextImpl
+	view   *text.View
+	reader *text.Reader
+}
+
+func newDiffSide(t *text.TextImpl) *diffSide {
+	charW, charH := gui.FontSize()
+	s := &diffSide{text: t}
+	s.view = text.NewView(t, 100, 0, charW, charH)
+	s.reader = s.view.Reader()
+	return s
+}
+
+// close removes the view from the text.
+func (s *diffSide) close() {
+	s.text.RemoveView(s.view)
+}
+
+// start positions the reader at the given line.
+func (s *diffSide) start(lineNum int) {
+	_, charH := gui.FontSize()
+	s.view.ScrollTo(0, (lineNum-1)*charH)
+	s.reader.TopLine()
+}
+
+// DiffView shows two revisions of a text side by side. Lines that differ only in color are flagged
+// separately from lines with changed characters.
+type DiffView struct {
+	gui.FrameImpl
+	a, b    *diffSide
+	rows    []text.DiffRow
+	scrollY int // Scroll in pixels
+}
+
+func NewDiffView(a, b *text.TextImpl) *DiffView {
+	d := &DiffView{
+		a:    newDiffSide(a),
+		b:    newDiffSide(b),
+		rows: text.Compare(a, b),
+	}
+	gui.InitFrame(&d.FrameImpl, 0, 0, 100, 100)
+	return d
+}
+
+// Close removes the views of the compared texts when the window is closed.
+func (d *DiffView) Close() {
+	d.a.close()
+	d.b.close()
+}
+
+// Summary returns the numbers of rows of each kind in a human-readable form.
+func (d *DiffView) Summary() string {
+	var count [text.RowAdded + 1]int
+	for _, row := range d.rows {
+		count[row.Kind]++
+	}
+	if count[text.RowEqual] == len(d.rows) {
+		return "No differences"
+	}
+	return fmt.Sprintf("%d changed, %d removed, %d added, %d recolored",
+		count[text.RowChanged], count[text.RowRemoved], count[text.RowAdded], count[text.RowColorChanged])
+}
+
+func (d *DiffView) scrollDelta(dy int) {
+	_, h := d.Size()
+	_, charH := gui.FontSize()
+	d.scrollY += dy
+	max := len(d.rows)*charH - h
+	if d.scrollY > max {
+		d.scrollY = max
+	}
+	if d.scrollY < 0 {
+		d.scrollY = 0
+	}
+}
+
+// nextChange scrolls to the next block of different rows after the top row, or to the previous one if backward.
+func (d *DiffView) nextChange(backward bool) {
+	_, charH := gui.FontSize()
+	top := d.scrollY / charH
+	i := top
+	step := 1
+	if backward {
+		step = -1
+	}
+	// Skip the block the top row belongs to
+	for i >= 0 && i < len(d.rows) && d.rows[i].Kind != text.RowEqual {
+		i += step
+	}
+	for i >= 0 && i < len(d.rows) && d.rows[i].Kind == text.RowEqual {
+		i += step
+	}
+	if backward {
+		for i > 0 && d.rows[i-1].Kind != text.RowEqual {
+			i--
+		}
+	}
+	if i >= 0 && i < len(d.rows) {
+		d.scrollY = i * charH
+		d.scrollDelta(0)
+	}
+}
+
+func (d *DiffView) OnKeyDown(key int, mod uint16) {
+	_, h := d.Size()
+	_, charH := gui.FontSize()
+	switch key {
+	case sdl.K_UP:
+		d.scrollDelta(-charH)
+	case sdl.K_DOWN:
+		d.scrollDelta(charH)
+	case sdl.K_PAGEUP:
+		d.scrollDelta(-h)
+	case sdl.K_PAGEDOWN:
+		d.scrollDelta(h)
+	case sdl.K_HOME:
+		d.scrollDelta(-d.scrollY)
+	case sdl.K_END:
+		d.scrollDelta(len(d.rows) * charH)
+	case sdl.K_F7, sdl.K_n:
+		d.nextChange(isShiftPressed(mod))
+	case sdl.K_p:
+		d.nextChange(true)
+	}
+}
+
+func (d *DiffView) MouseWheel(x, y int, wx, wy float32, inverted bool) {
+	if inverted {
+		wy = -wy
+	}
+	d.scrollDelta(int(wy * scrollSensitivity))
+}
+
+func (d *DiffView) MouseDown(x, y, button int) {
+	gui.SetFocus(d)
+}
+
+// rowColors returns the background colors of both sides of a row and the flag shown between them.
+func rowColors(kind int) (bgA, bgB color.Color, flag string, flagColor color.Color) {
+	switch kind {
+	case text.RowColorChanged:
+		return color.Transparent, color.Transparent, "~", diffColorFlagColor
+	case text.RowChanged:
+		return diffRemovedBgColor, diffAddedBgColor, "!", diffTextFlagColor
+	case text.RowRemoved:
+		return diffRemovedBgColor, diffFillerColor, "-", diffTextFlagColor
+	case text.RowAdded:
+		return diffFillerColor, diffAddedBgColor, "+", diffTextFlagColor
+	}
+	return color.Transparent, color.Transparent, "", color.Transparent
+}
+
+func (d *DiffView) Render(x, y int) {
+	w, h := d.Size()
+	charW, charH := gui.FontSize()
+	const gutterChars = 3
+
+	gui.SetColor(theme.Chrome.Background)
+	gui.Renderer.FillRect(&sdl.Rect{X: int32(x), Y: int32(y), W: int32(w), H: int32(h)})
+
+	sideW := (w - gutterChars*charW) / 2
+	xA, xGutter := x, x+sideW
+	xB := xGutter + gutterChars*charW
+
+	first := d.scrollY / charH
+	Y := y - d.scrollY%charH
+	startedA, startedB := false, false
+	for i := first; i < len(d.rows) && Y < y+h; i++ {
+		row := d.rows[i]
+		bgA, bgB, flag, flagColor := rowColors(row.Kind)
+		if flag != "" {
+			gui.SetColor(flagColor)
+			gui.Renderer.FillRect(&sdl.Rect{X: int32(xGutter + 2), Y: int32(Y), W: int32(gutterChars*charW - 4), H: int32(charH)})
+			gui.PrintCentered(flag, xGutter, Y, gutterChars*charW, charH, color.Black, color.Transparent)
+		}
+		if row.LineA != 0 {
+			if !startedA {
+				d.a.start(row.LineA)
+				startedA = true
+			}
+			d.renderLine(d.a, row.LineA, xA, Y, sideW, bgA)
+			d.a.reader.NextLine()
+		} else {
+			d.fillLine(xA, Y, sideW, bgA)
+		}
+		if row.LineB != 0 {
+			if !startedB {
+				d.b.start(row.LineB)
+				startedB = true
+			}
+			d.renderLine(d.b, row.LineB, xB, Y, sideW, bgB)
+			d.b.reader.NextLine()
+		} else {
+			d.fillLine(xB, Y, sideW, bgB)
+		}
+		Y += charH
+	}
+}
+
+func (d *DiffView) fillLine(x, y, w int, bgColor color.Color) {
+	_, charH := gui.FontSize()
+	gui.SetColor(bgColor)
+	gui.Renderer.FillRect(&sdl.Rect{X: int32(x), Y: int32(y), W: int32(w), H: int32(charH)})
+}
+
+// renderLine renders the current line of the reader of side s with its line number.
+func (d *DiffView) renderLine(s *diffSide, lineNum, x, y, w int, bgColor color.Color) {
+	charW, charH := gui.FontSize()
+	const numberChars = 5
+	if bgColor != color.Transparent {
+		d.fillLine(x, y, w, bgColor)
+	}
+	gui.Print(fmt.Sprintf("%4d", lineNum), x, y, theme.Chrome.LineNumber, color.Transparent)
+
+	rect := sdl.Rect{X: int32(x), Y: int32(y), W: int32(w), H: int32(charH)}
+	clip := gui.Renderer.GetClipRect()
+	clipEnabled := gui.Renderer.IsClipEnabled()
+	if clipEnabled {
+		rect, _ = rect.Intersect(&clip)
+	}
+	gui.Renderer.SetClipRect(&rect)
+
+	tabSize := s.text.TabSize()
+	X0 := x + numberChars*charW
+	X := X0
+	visualX := 0
+	char, ok := s.reader.FirstChar()
+	for ok {
+		charCount := 1
+		if char.Char == '\t' {
+			charCount = tabSize - visualX%tabSize
+		}
+		if char.BgColor == color.Transparent {
+			char.BgColor = bgColor
+		}
+		for j := 0; j < charCount; j++ {
+			r := char.Char
+			if j != 0 || r == '\t' {
+				r = ' '
+			}
+			gui.PrintChar(r, X+j*charW, y, char.Color, char.BgColor)
+		}
+		visualX += charCount
+		X += charW * charCount
+		char, ok = s.reader.NextChar()
+	}
+	restColor := bgColor
+	if s.reader.ShouldPaintFullLine(&restColor) {
+		gui.SetColor(restColor)
+		gui.Renderer.FillRect(&sdl.Rect{X: int32(X), Y: int32(y), W: int32(x + w - X), H: int32(charH)})
+	}
+
+	if clipEnabled {
+		gui.Renderer.SetClipRect(&clip)
+	} else {
+		gui.Renderer.SetClipRect(nil)
+	}
+}
@@ -0,0 +1,67 @@
+package editor
+
+import (
+	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/text"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// DiffWindow is the main frame of the "coloride diff" mode.
+type DiffWindow struct {
+	gui.FrameImpl
+
+	labelA, labelB *gui.Label
+	summaryLabel   *gui.Label
+	view           *DiffView
+}
+
+// NewDiffWindow creates a window that compares texts a and b loaded from files fnameA and fnameB.
+func NewDiffWindow(a, b *text.TextImpl, fnameA, fnameB string) *DiffWindow {
+	win := &DiffWindow{}
+	gui.InitFrame(&win.FrameImpl, 0, 0, 100, 100)
+
+	win.labelA = gui.NewLabel(fnameA, 0, 0, 100, 32)
+	win.labelB = gui.NewLabel(fnameB, 0, 0, 100, 32)
+	win.view = NewDiffView(a, b)
+	win.summaryLabel = gui.NewLabel(win.view.Summary(), 0, 0, 100, 32)
+
+	win.Append(win.labelA)
+	win.Append(win.labelB)
+	win.Append(win.view)
+	win.Append(win.summaryLabel)
+
+	gui.SetWindowTitle(fnameA + " ↔ " + fnameB + " - ColorIDE")
+	gui.OnQuit(func() {
+		win.view.Close()
+		gui.Quit()
+	})
+	return win
+}
+
+func (win *DiffWindow) ResizeInside() {
+	w, h := win.Size()
+	const headerH = 40
+	const footerH = 40
+
+	frame := 16
+	X, Y, W, H := frame, frame, w-2*frame, h-2*frame
+	gui.SetGeometry(win.labelA, X, Y+8, W/2, headerH-8)
+	gui.SetGeometry(win.labelB, X+W/2, Y+8, W-W/2, headerH-8)
+	gui.SetGeometry(win.view, X, Y+headerH, W, H-headerH-footerH)
+	gui.SetGeometry(win.summaryLabel, X, Y+H-footerH+8, W, footerH-8)
+}
+
+func (win *DiffWindow) Render(x, y int) {
+	w, h := win.Size()
+	rect := sdl.Rect{X: int32(x), Y: int32(y), W: int32(w), H: int32(h)}
+
+	gui.SetColor(win.BgColor())
+	gui.Renderer.FillRect(&rect)
+
+	win.RenderChildren(x, y)
+}
+
+// View returns the frame that should have the keyboard focus.
+func (win *DiffWindow) View() *DiffView {
+	return win.view
+}
@@ -0,0 +1,202 @@
+package editor
+
+import (
+	"path/filepath"
+
+	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/text"
+	"github.com/patrikaleksandryan/coloride/pkg/theme"
+)
+
+// document is a text opened in a tab of the editor.
+type document struct {
+	editor   *Editor
+	text     text.Text
+	view     *text.View
+	fname    string // "" if the file was not saved yet
+	edited   bool
+	readOnly bool
+	semantic semanticAnalysis
+	journal  documentJournal
+}
+
+func newDocument(e *Editor) *document {
+	charW, charH := gui.FontSize()
+	w, h := e.viewSize()
+	d := &document{
+		editor: e,
+		text:   text.NewText(),
+	}
+	d.view = text.NewView(d.text.(*text.TextImpl), w, h, charW, charH)
+	d.text.SetNewLineType(theme.NewLineType)
+	d.text.SetUpdaters(d, d)
+	d.text.SetClipboard(gui.Clipboard{})
+	d.initSemantic()
+	return d
+}
+
+// close releases the document when its tab is closed.
+func (d *document) close() {
+	d.removeJournal()
+	d.text.RemoveView(d.view)
+}
+
+// name returns the base name of the file of the document.
+func (d *document) name() string {
+	if d.fname == "" {
+		return "Untitled"
+	}
+	return filepath.Base(d.fname)
+}
+
+// caption returns the caption of the tab of the document.
+func (d *document) caption() string {
+	if d.readOnly {
+		return d.name() + " (read-only)"
+	} else if d.edited {
+		return d.name() + " *"
+	}
+	return d.name()
+}
+
+// isBlank reports whether the document is an unchanged new file, which can be replaced by an opened one.
+func (d *document) isBlank() bool {
+	return d.fname == "" && d.text.Version() == 0
+}
+
+func (d *document) active() bool {
+	return d.editor.doc == d
+}
+
+func (d *document) UpdateEdited(edited bool) {
+	d.edited = edited
+	if !edited {
+		d.removeJournal()
+	}
+	if d.active() && d.editor.editedUpdater != nil {
+		d.editor.editedUpdater.UpdateEdited(edited)
+	}
+	d.editor.updateTabs()
+}
+
+func (d *document) UpdatePos(line, col int) {
+	if d.active() && d.editor.posUpdater != nil {
+		d.editor.posUpdater.UpdatePos(line, col)
+	}
+}
+
+// Editor
+
+// addDocument adds a tab for the document after the active one and activates it.
+func (e *Editor) addDocument(d *document) {
+	i := len(e.docs)
+	if e.doc != nil {
+		i = e.docIndex(e.doc) + 1
+	}
+	e.docs = append(e.docs[:i], append([]*document{d}, e.docs[i:]...)...)
+	e.activate(d)
+}
+
+// replaceDocument puts the document d in place of old.
+func (e *Editor) replaceDocument(old, d *document) {
+	e.docs[e.docIndex(old)] = d
+	old.close()
+	e.activate(d)
+}
+
+// docIndex returns the index of the tab of the document.
+func (e *Editor) docIndex(d *document) int {
+	for i, doc := range e.docs {
+		if doc == d {
+			return i
+		}
+	}
+	return -1
+}
+
+// activate shows the document in the editor.
+func (e *Editor) activate(d *document) {
+	e.doc = d
+	d.view.Resize(e.viewSize())
+	e.UpdateTitles()
+	if e.editedUpdater != nil {
+		e.editedUpdater.UpdateEdited(d.edited)
+	}
+	if e.posUpdater != nil {
+		e.posUpdater.UpdatePos(d.text.CurLineNum(), d.text.CursorX()+1)
+	}
+}
+
+// SelectTab activates the document of the i-th tab.
+func (e *Editor) SelectTab(i int) {
+	if 0 <= i && i < len(e.docs) {
+		e.activate(e.docs[i])
+	}
+}
+
+// NextTab activates the next or the previous tab, wrapping around.
+func (e *Editor) NextTab(backward bool) {
+	n := len(e.docs)
+	i := e.docIndex(e.doc)
+	if backward {
+		e.SelectTab((i + n - 1) % n)
+	} else {
+		e.SelectTab((i + 1) % n)
+	}
+}
+
+// CloseTab closes the document of the i-th tab, asking first if it has unsaved changes.
+// The last tab is replaced by an empty one.
+func (e *Editor) CloseTab(i int) {
+	if i < 0 || i >= len(e.docs) {
+		return
+	}
+	d := e.docs[i]
+	e.unsaved.confirm(d, func() { e.removeDocument(d) })
+}
+
+// ConfirmQuit asks about every document with unsaved changes whether to save it, and calls quit
+// unless the user cancels. The recovery journals are not needed after that.
+func (e *Editor) ConfirmQuit(quit func()) {
+	e.unsaved.confirmAll(e.docs, func() {
+		e.removeJournals()
+		quit()
+	})
+}
+
+// askSave shows the document and asks whether to save its changes.
+func (e *Editor) askSave(d *document, answer func(choice int)) {
+	e.activate(d)
+	dlg := gui.NewDialog(d.name()+" has unsaved changes.", "Save", "Discard", "Cancel")
+	dlg.OnChoose = answer
+	gui.ShowModal(dlg)
+}
+
+// removeDocument closes the tab of the document without asking.
+func (e *Editor) removeDocument(d *document) {
+	i := e.docIndex(d)
+	if i == -1 {
+		return
+	}
+	d.close()
+	e.docs = append(e.docs[:i], e.docs[i+1:]...)
+	if len(e.docs) == 0 {
+		e.doc = nil
+		e.addDocument(newDocument(e))
+	} else if d == e.doc {
+		e.activate(e.docs[min(i, len(e.docs)-1)])
+	} else {
+		e.updateTabs()
+	}
+}
+
+func (e *Editor) updateTabs() {
+	if e.tabsUpdater == nil || e.doc == nil {
+		return
+	}
+	captions := make([]string, len(e.docs))
+	for i, d := range e.docs {
+		captions[i] = d.caption()
+	}
+	e.tabsUpdater.UpdateTabs(captions, e.docIndex(e.doc))
+}
@@ -2,13 +2,16 @@ package editor
 
 import (
 	"fmt"
-	"log"
+	"io"
 	"path/filepath"
+	"time"
 
 	"github.com/patrikaleksandryan/coloride/pkg/color"
 	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/palette"
 	"github.com/patrikaleksandryan/coloride/pkg/syntax"
 	"github.com/patrikaleksandryan/coloride/pkg/text"
+	"github.com/patrikaleksandryan/coloride/pkg/theme"
 	"github.com/veandco/go-sdl2/sdl"
 
 	"github.com/ncruces/zenity"
@@ -23,35 +26,56 @@ type Editor struct {
 
 	borderWidth     int
 	sidebarWidth    int
-	text            text.Text
-	fname           string
+	docs            []*document // Open documents in the order of tabs
+	doc             *document   // Active document
 	fileNameUpdater FileNameUpdater
+	editedUpdater   text.EditedUpdater
+	posUpdater      text.PosUpdater
+	tabsUpdater     TabsUpdater
+	formatUpdater   FormatUpdater
+	formatDoc       *document // Document shown by formatUpdater
+	formatVersion   int       // Version of formatDoc when it was shown
+
+	sameColorRuns bool // Whether NextColorRun keeps to the color at the cursor
+	semantic      bool // Whether semantic highlighting is on
+	recovery      recovery
+	unsaved       unsavedChanges
+	filesChecked  time.Time // When the files of the documents were checked for changes last
+
+	OnFind func() // Called on Ctrl+F
 }
 
 type FileNameUpdater interface {
 	UpdateFileName(fname string)
 }
 
-func NewEditor(fileNameUpdater FileNameUpdater,
-	editedUpdater text.EditedUpdater, posUpdater text.PosUpdater) *Editor {
-	charW, charH := gui.FontSize()
+func NewEditor(fileNameUpdater FileNameUpdater, editedUpdater text.EditedUpdater, posUpdater text.PosUpdater,
+	tabsUpdater TabsUpdater, formatUpdater FormatUpdater) *Editor {
 	e := &Editor{
 		borderWidth:     4,
 		sidebarWidth:    64,
-		text:            text.NewText(100, 100, charW, charH),
 		fileNameUpdater: fileNameUpdater,
+		editedUpdater:   editedUpdater,
+		posUpdater:      posUpdater,
+		tabsUpdater:     tabsUpdater,
+		formatUpdater:   formatUpdater,
+		recovery:        recovery{dir: recoveryDir()},
 	}
-
-	e.text.SetUpdaters(editedUpdater, posUpdater)
-
-	err := e.text.LoadFromFile("data/sample.go")
-	if err != nil {
-		panic(fmt.Errorf("could not load file: %w", err))
+	e.unsaved = unsavedChanges{
+		ask: e.askSave,
+		save: func(d *document, done func()) {
+			e.activate(d)
+			e.saveDocument(d, done)
+		},
 	}
-	e.fname = "sample.go"
-	e.UpdateTitles()
-
 	gui.InitFrame(&e.FrameImpl, 0, 0, 100, 100)
+
+	e.addDocument(newDocument(e))
+	gui.OnTick(e.updateSemantic)
+	gui.OnTick(e.updateFormat)
+	gui.OnTick(e.updateJournals)
+	gui.OnTick(e.checkFiles)
+	gui.OnWindowFocus(e.CheckFiles)
 	return e
 }
 
@@ -62,57 +86,100 @@ func isShiftPressed(mod uint16) bool {
 func (e *Editor) OnKeyDown(key int, mod uint16) {
 	switch key {
 	case sdl.K_LEFT:
-		e.text.HandleLeft(isShiftPressed(mod))
+		e.doc.text.HandleLeft(isShiftPressed(mod))
 	case sdl.K_RIGHT:
-		e.text.HandleRight(isShiftPressed(mod))
+		e.doc.text.HandleRight(isShiftPressed(mod))
 	case sdl.K_UP:
-		e.text.HandleUp(isShiftPressed(mod))
+		e.doc.text.HandleUp(isShiftPressed(mod))
 	case sdl.K_DOWN:
-		e.text.HandleDown(isShiftPressed(mod))
+		e.doc.text.HandleDown(isShiftPressed(mod))
 	case sdl.K_PAGEUP:
-		e.text.HandlePageUp(isShiftPressed(mod))
+		e.doc.text.HandlePageUp(isShiftPressed(mod))
 	case sdl.K_PAGEDOWN:
-		e.text.HandlePageDown(isShiftPressed(mod))
+		e.doc.text.HandlePageDown(isShiftPressed(mod))
 	case sdl.K_HOME:
-		e.text.HandleHome(isShiftPressed(mod))
+		e.doc.text.HandleHome(isShiftPressed(mod))
 	case sdl.K_END:
-		e.text.HandleEnd(isShiftPressed(mod))
+		e.doc.text.HandleEnd(isShiftPressed(mod))
 	case sdl.K_ESCAPE:
-		e.text.HandleEscape()
+		e.doc.text.HandleEscape()
 	case sdl.K_x:
-		if gui.IsCtrlCmdPressed(mod) {
-			e.text.HandleCut()
+		if gui.IsCtrlCmdPressed(mod) && e.editable() {
+			e.doc.text.HandleCut()
 		}
 	case sdl.K_c:
 		if gui.IsCtrlCmdPressed(mod) {
-			e.text.HandleCopy()
+			e.doc.text.HandleCopy()
 		}
 	case sdl.K_v:
-		if gui.IsCtrlCmdPressed(mod) {
-			e.text.HandlePaste()
+		if gui.IsCtrlCmdPressed(mod) && e.editable() {
+			e.doc.text.HandlePaste()
 		}
 	case sdl.K_a:
 		if gui.IsCtrlCmdPressed(mod) {
-			e.text.HandleSelectAll()
+			e.doc.text.HandleSelectAll()
+		}
+	case sdl.K_f:
+		if gui.IsCtrlCmdPressed(mod) && e.OnFind != nil {
+			e.OnFind()
+		}
+	case sdl.K_F3:
+		if isShiftPressed(mod) {
+			e.FindPrev()
+		} else {
+			e.FindNext()
+		}
+	case sdl.K_F8:
+		if gui.IsCtrlCmdPressed(mod) {
+			e.findSameColorRun(isShiftPressed(mod))
+		} else {
+			e.NextColorRun(isShiftPressed(mod))
+		}
+	case sdl.K_F9:
+		e.ToggleSemantic()
+	case sdl.K_TAB:
+		if gui.IsCtrlCmdPressed(mod) {
+			e.NextTab(isShiftPressed(mod))
+		}
+	case sdl.K_w:
+		if gui.IsCtrlCmdPressed(mod) {
+			e.CloseTab(e.docIndex(e.doc))
 		}
-	case sdl.K_0, sdl.K_1, sdl.K_2, sdl.K_3, sdl.K_4, sdl.K_5, sdl.K_6, sdl.K_7, sdl.K_8:
+	case sdl.K_z:
+		if gui.IsCtrlCmdPressed(mod) && e.editable() {
+			if isShiftPressed(mod) {
+				e.doc.text.HandleRedo()
+			} else {
+				e.doc.text.HandleUndo()
+			}
+		}
+	case sdl.K_0, sdl.K_1, sdl.K_2, sdl.K_3, sdl.K_4, sdl.K_5, sdl.K_6, sdl.K_7, sdl.K_8, sdl.K_9:
 		if gui.IsCtrlCmdPressed(mod) {
+			// Ctrl+Shift+digit selects colors from 10 on
 			keyColor := key - sdl.K_0
-			e.text.ColorizeSelection(keyColor)
+			if isShiftPressed(mod) {
+				keyColor += 10
+			}
+			if keyColor < palette.Count() {
+				e.ColorizeSelection(keyColor)
+			}
 		}
 	}
 }
 
 func (e *Editor) OnCharInput(r rune) {
+	if !e.editable() {
+		return
+	}
 	switch r {
 	case text.KeyBackspace:
-		e.text.HandleBackspace()
+		e.doc.text.HandleBackspace()
 	case text.KeyDelete:
-		e.text.HandleDelete()
+		e.doc.text.HandleDelete()
 	case text.KeyEnter:
-		e.text.HandleEnter()
+		e.doc.text.HandleEnter()
 	default:
-		e.text.HandleChar(r)
+		e.doc.text.HandleChar(r)
 	}
 }
 
@@ -123,20 +190,38 @@ func (e *Editor) renderCursor(x, y int, color color.Color) {
 	gui.Renderer.FillRect(&rect)
 }
 
+// renderLineMarks draws marks of the colors used in the line at the right edge of the line number gutter.
+func (e *Editor) renderLineMarks(x, y, lineNum int) {
+	const markW = 4
+	const gap = 2
+	const maxMarks = 3
+	_, charH := gui.FontSize()
+	line, _ := e.doc.text.LineByNum(lineNum)
+	X := x + e.sidebarWidth - markW - gap
+	for i, clr := range line.Colors() {
+		if i == maxMarks {
+			break
+		}
+		gui.SetColor(palette.Get(clr).Mark)
+		gui.Renderer.FillRect(&sdl.Rect{X: int32(X), Y: int32(y + 1), W: markW, H: int32(charH - 2)})
+		X -= markW + gap
+	}
+}
+
 func (e *Editor) DrawFrame(x, y int) {
 	w, h := e.Size()
 	x += e.sidebarWidth
 	w -= e.sidebarWidth
 	size := e.borderWidth
 
-	gui.SetColor(color.Black)
+	gui.SetColor(theme.Chrome.Background)
 	rect := sdl.Rect{X: int32(x), Y: int32(y), W: int32(w), H: int32(h)}
 	gui.Renderer.FillRect(&rect)
 
-	gui.SetRGB(113, 92, 72)
+	gui.SetColor(theme.Chrome.BorderDark)
 	gui.Renderer.FillRect(&sdl.Rect{X: int32(x), Y: int32(y), W: int32(w), H: int32(size)})
 	gui.Renderer.FillRect(&sdl.Rect{X: int32(x), Y: int32(y), W: int32(size), H: int32(h)})
-	gui.SetRGB(235, 235, 207)
+	gui.SetColor(theme.Chrome.BorderLight)
 	gui.Renderer.FillRect(&sdl.Rect{X: int32(x + size), Y: int32(y + h - size), W: int32(w - size), H: int32(size)})
 	gui.Renderer.FillRect(&sdl.Rect{X: int32(x + w - size), Y: int32(y + size), W: int32(size), H: int32(h - size)})
 }
@@ -144,15 +229,16 @@ func (e *Editor) DrawFrame(x, y int) {
 func (e *Editor) Render(x, y int) {
 	w, h := e.Size()
 
-	selColor := color.MakeColor(255, 255, 255)
-	selBgColor := color.MakeColor(0, 0, 255)
-	selBgColor2 := color.MakeColor(40, 90, 160)
-	lineNumberColor := color.MakeColor(125, 89, 69)
-	curLineNumberColor := color.MakeColor(235, 235, 203)
-	tabSize := e.text.TabSize()
-	cursorX := e.text.CursorX()
+	selColor := theme.Chrome.Selection
+	selBgColor := theme.Chrome.SelectionBg
+	selBgColor2 := theme.Chrome.SelectionColoredBg
+	foundBgColor := theme.Chrome.FoundBg
+	lineNumberColor := theme.Chrome.LineNumber
+	curLineNumberColor := theme.Chrome.CurLineNumber
+	tabSize := e.doc.text.TabSize()
+	cursorX := e.doc.text.CursorX()
 	charW, charH := gui.FontSize()
-	_, scrollY := e.text.ScrollValues()
+	_, scrollY := e.doc.view.ScrollValues()
 	border := e.borderWidth
 	X0, Y := x+e.borderWidth+e.sidebarWidth, y-scrollY+border
 	X := X0
@@ -163,16 +249,18 @@ func (e *Editor) Render(x, y int) {
 	gui.Renderer.SetClipRect(&rect)
 	clr := text.SymbolClassToColor(syntax.CNone)
 
-	curLineNum := e.text.CurLineNum()
-	reader := e.text.Reader()
+	curLineNum := e.doc.text.CurLineNum()
+	reader := e.doc.view.Reader()
 	lineNum := reader.TopLine()
-	for lineNum != -1 { // -1 means "no more lines", returned by NextLine
+	Y += (lineNum - 1) * charH
+	for lineNum != -1 && Y < y+h { // -1 means "no more lines", returned by NextLine
 
 		numColor := lineNumberColor
 		if lineNum == curLineNum {
 			numColor = curLineNumberColor
 		}
 		gui.Print(fmt.Sprintf("%03d", lineNum), x, Y, numColor, color.Transparent)
+		e.renderLineMarks(x, Y, lineNum)
 
 		visualX := 0
 		i := 0
@@ -184,13 +272,15 @@ func (e *Editor) Render(x, y int) {
 				charCount = tabSize - visualX%tabSize
 			}
 
-			if e.text.InSelection(lineNum, i) {
+			if e.doc.text.InSelection(lineNum, i) {
 				char.Color = selColor
 				if char.BgColor != color.Transparent {
 					char.BgColor = selBgColor2
 				} else {
 					char.BgColor = selBgColor
 				}
+			} else if char.Found {
+				char.BgColor = foundBgColor
 			}
 
 			gui.PrintChar(char.Char, X, Y, char.Color, char.BgColor)
@@ -209,7 +299,7 @@ func (e *Editor) Render(x, y int) {
 			i++
 		}
 		restColor := selBgColor
-		inSelection := e.text.InSelection(lineNum+1, -1)
+		inSelection := e.doc.text.InSelection(lineNum+1, -1)
 		paintRest := reader.ShouldPaintFullLine(&restColor)
 		if inSelection || paintRest {
 			rect := sdl.Rect{X: int32(X), Y: int32(Y), W: int32(x + w - X), H: int32(charH)}
@@ -228,19 +318,26 @@ func (e *Editor) Render(x, y int) {
 	}
 }
 
+// viewSize returns the size of the area where the text is shown.
+func (e *Editor) viewSize() (w, h int) {
+	w, h = e.Size()
+	return w - 2*e.borderWidth, h - 2*e.borderWidth
+}
+
 func (e *Editor) ResizeInside() {
-	w, h := e.Size()
-	e.text.Resize(w-2*e.borderWidth, h-2*e.borderWidth)
+	for _, d := range e.docs {
+		d.view.Resize(e.viewSize())
+	}
 }
 
 func (e *Editor) jumpToMouse(x, y int) {
 	charW, charH := gui.FontSize()
-	_, scrollY := e.text.ScrollValues()
+	_, scrollY := e.doc.view.ScrollValues()
 	lineNum := (y+scrollY)/charH + 1
-	line, lineNum := e.text.LineByNum(lineNum)
-	cursorX := e.text.VisualToCursorX(line, (x-e.sidebarWidth+charW/2-1)/charW)
-	e.text.SetCurLine(line, lineNum)
-	e.text.SetCursorX(cursorX)
+	line, lineNum := e.doc.text.LineByNum(lineNum)
+	cursorX := e.doc.text.VisualToCursorX(line, (x-e.sidebarWidth+charW/2-1)/charW)
+	e.doc.text.SetCurLine(line, lineNum)
+	e.doc.text.SetCursorX(cursorX)
 }
 
 func (e *Editor) MouseWheel(x, y int, wx, wy float32, inverted bool) {
@@ -248,7 +345,7 @@ func (e *Editor) MouseWheel(x, y int, wx, wy float32, inverted bool) {
 		wy = -wy
 	}
 
-	e.text.ScrollDelta(int(wy * scrollSensitivity))
+	e.doc.view.ScrollDelta(int(wy * scrollSensitivity))
 
 	if e.OnMouseWheel != nil {
 		e.OnMouseWheel(x, y, wx, wy, inverted)
@@ -256,45 +353,184 @@ func (e *Editor) MouseWheel(x, y int, wx, wy float32, inverted bool) {
 }
 
 func (e *Editor) MouseDown(x, y, button int) {
+	gui.SetFocus(e)
 	if button == 1 {
 		e.jumpToMouse(x-e.borderWidth, y-e.borderWidth)
-		e.text.StartMouseSelection()
+		e.doc.text.StartMouseSelection()
 	}
 }
 
 func (e *Editor) MouseMove(x, y int, buttons uint32) {
 	if buttons&1 != 0 {
 		e.jumpToMouse(x-e.borderWidth, y-e.borderWidth)
-		e.text.ContinueMouseSelection()
+		e.doc.text.ContinueMouseSelection()
 	}
 }
 
 func (e *Editor) ColorizeSelection(color int) {
-	e.text.ColorizeSelection(color)
+	if !e.editable() {
+		return
+	}
+	e.doc.text.ColorizeSelection(color)
 }
 
-func (e *Editor) LoadFromFile(fname string) {
-	err := e.text.LoadFromFile(fname)
+func (e *Editor) Find(query string, options text.SearchOptions) (count int, err error) {
+	if query == "" {
+		e.doc.text.SetSearch(nil)
+		return 0, nil
+	}
+	search, err := text.NewSearch(query, options)
 	if err != nil {
-		log.Fatal(err)
+		e.doc.text.SetSearch(nil)
+		return 0, err
+	}
+	e.doc.text.SetSearch(search)
+	e.doc.text.FindIncremental()
+	return e.doc.text.CountMatches(), nil
+}
+
+func (e *Editor) FindNext() {
+	e.doc.text.FindNext()
+}
+
+// FindColorRun selects the next region of the given color.
+func (e *Editor) FindColorRun(clr int) {
+	e.doc.text.FindColorRun(clr, false)
+}
+
+// NextColorRun selects the next colored region, or the previous one if backward.
+// If SetSameColorRuns is on, only regions of the color at the cursor are visited.
+func (e *Editor) NextColorRun(backward bool) {
+	if e.sameColorRuns {
+		e.findSameColorRun(backward)
+	} else {
+		e.doc.text.FindColorRun(text.AnyColor, backward)
+	}
+}
+
+func (e *Editor) SetSameColorRuns(on bool) {
+	e.sameColorRuns = on
+}
+
+// findSameColorRun selects the next region of the color at the cursor, or of any color if there is none.
+func (e *Editor) findSameColorRun(backward bool) {
+	clr := e.doc.text.CursorColor()
+	if clr == 0 {
+		clr = text.AnyColor
+	}
+	e.doc.text.FindColorRun(clr, backward)
+}
+
+func (e *Editor) FindPrev() {
+	e.doc.text.FindPrev()
+}
+
+func (e *Editor) Replace(replacement string) {
+	if !e.editable() {
 		return
 	}
-	e.fname = fname
+	e.doc.text.Replace(replacement)
+}
+
+func (e *Editor) ReplaceAll(replacement string) int {
+	if !e.editable() {
+		return 0
+	}
+	return e.doc.text.ReplaceAll(replacement)
+}
+
+func (e *Editor) CountMatches() int {
+	return e.doc.text.CountMatches()
+}
+
+func (e *Editor) CloseSearch() {
+	e.doc.text.SetSearch(nil)
+}
+
+// LoadFromFile opens the file in a new tab, or selects its tab if it is already open.
+// Reports false if the file could not be opened.
+func (e *Editor) LoadFromFile(fname string) bool {
+	for _, d := range e.docs {
+		if d.fname != "" && sameFile(d.fname, fname) {
+			e.activate(d)
+			return true
+		}
+	}
+	d := newDocument(e)
+	err := d.text.LoadFromFile(fname)
+	if err != nil {
+		e.showError("Could not open "+filepath.Base(fname), err)
+		return false
+	}
+	d.fname = fname
+	e.openDocument(d)
+	return true
+}
+
+// LoadFromReader opens the contents of r, i.e. stdin, in a new tab as an unsaved file.
+// Reports false if r could not be read.
+func (e *Editor) LoadFromReader(r io.Reader) bool {
+	d := newDocument(e)
+	err := d.text.Load(r)
+	if err != nil {
+		e.showError("Could not read input", err)
+		return false
+	}
+	e.openDocument(d)
+	return true
+}
+
+// openDocument shows the loaded document in place of the blank one, or in a new tab.
+func (e *Editor) openDocument(d *document) {
+	if e.doc.isBlank() {
+		e.replaceDocument(e.doc, d)
+	} else {
+		e.addDocument(d)
+	}
+}
+
+// GoToLine moves the cursor to the beginning of the line lineNum of the active document.
+func (e *Editor) GoToLine(lineNum int) {
+	line, lineNum := e.doc.text.LineByNum(lineNum)
+	e.doc.text.SetCurLine(line, lineNum)
+}
+
+// SetReadOnly sets whether the active document can be edited.
+func (e *Editor) SetReadOnly(readOnly bool) {
+	e.doc.readOnly = readOnly
+	e.updateTabs()
+}
+
+// editable reports whether the active document can be edited.
+func (e *Editor) editable() bool {
+	return !e.doc.readOnly
 }
 
+// sameFile reports whether both names refer to the same file.
+func sameFile(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+// SaveToFile saves the active document as fname. Errors are shown to the user, the document stays unchanged.
 func (e *Editor) SaveToFile(fname string) {
-	err := e.text.SaveToFile(fname)
+	err := e.doc.text.SaveToFile(fname)
 	if err != nil {
-		log.Fatal(err)
+		e.showError("Could not save "+filepath.Base(fname), err)
 		return
 	}
-	e.fname = fname
+	e.doc.fname = fname
+}
+
+// showError tells the user about an error in a dialog. The message says what has failed.
+func (e *Editor) showError(message string, err error) {
+	gui.ShowModal(gui.NewDialog(message+": "+err.Error(), "OK"))
 }
 
+// NewFile opens an empty text in a new tab.
 func (e *Editor) NewFile() {
-	e.text.Clear()
-	e.fname = ""
-	e.UpdateTitles()
+	e.addDocument(newDocument(e))
 }
 
 func (e *Editor) OpenFile() {
@@ -309,15 +545,32 @@ func (e *Editor) OpenFile() {
 		return
 	}
 	e.LoadFromFile(fname)
-	e.UpdateTitles()
 }
 
 func (e *Editor) SaveFile() {
-	if e.fname != "" {
-		e.SaveToFile(e.fname)
-	} else {
+	e.saveDocument(e.doc, func() {})
+}
+
+// saveDocument saves the active document d and then calls done, also if d has not been saved. If the file
+// has been changed by another program, the user is asked first, and d is saved only if they keep it.
+func (e *Editor) saveDocument(d *document, done func()) {
+	if d.fname == "" {
 		e.SaveFileAs()
+		done()
+		return
+	}
+	// Do not overwrite changes of the file made by another program unnoticed
+	if changed, _ := d.text.FileChanged(d.fname); changed {
+		e.askReload(d, func(kept bool) {
+			if kept {
+				e.SaveToFile(d.fname)
+			}
+			done()
+		})
+		return
 	}
+	e.SaveToFile(d.fname)
+	done()
 }
 
 func (e *Editor) SaveFileAs() {
@@ -335,15 +588,21 @@ func (e *Editor) SaveFileAs() {
 	e.UpdateTitles()
 }
 
+// FileName returns the name of the edited file, or "" if the file was not saved yet.
+func (e *Editor) FileName() string {
+	return e.doc.fname
+}
+
 func (e *Editor) UpdateTitles() {
 	const name = "ColorIDE"
-	if e.fname == "" {
+	if e.doc.fname == "" {
 		gui.SetWindowTitle(name)
 	} else {
-		gui.SetWindowTitle(filepath.Base(e.fname) + " - " + name)
+		gui.SetWindowTitle(filepath.Base(e.doc.fname) + " - " + name)
 	}
 
 	if e.fileNameUpdater != nil {
-		e.fileNameUpdater.UpdateFileName(e.fname)
+		e.fileNameUpdater.UpdateFileName(e.doc.fname)
 	}
+	e.updateTabs()
 }
@@ -0,0 +1,98 @@
+package editor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/patrikaleksandryan/coloride/pkg/gui"
+)
+
+const (
+	fileCheckInterval = 2 * time.Second // How often the files of the documents are checked for changes
+)
+
+// checkFiles is called every frame. It checks the files every fileCheckInterval.
+func (e *Editor) checkFiles() {
+	if time.Since(e.filesChecked) >= fileCheckInterval {
+		e.CheckFiles()
+	}
+}
+
+// CheckFiles looks for documents whose files have been changed by other programs, i.e. by git checkout or by
+// a formatter. Documents without unsaved changes are reloaded, otherwise the user is asked what to do.
+// Files that cannot be read, i.e. have been deleted, are left alone.
+func (e *Editor) CheckFiles() {
+	e.filesChecked = time.Now()
+	if gui.Modal() != nil {
+		return // One question at a time, the other documents are checked later
+	}
+	for _, d := range e.docs {
+		if d.fname == "" {
+			continue
+		}
+		changed, err := d.text.FileChanged(d.fname)
+		if err != nil || !changed {
+			continue
+		}
+		if !d.edited {
+			e.reload(d)
+		} else {
+			e.askReload(d, nil)
+			return
+		}
+	}
+}
+
+// reload loads the document from its file again, keeping the cursor line and the scroll position.
+func (e *Editor) reload(d *document) {
+	lineNum := d.text.CurLineNum()
+	scrollX, scrollY := d.view.ScrollValues()
+	err := d.text.LoadFromFile(d.fname)
+	if err != nil {
+		e.showError("Could not reload "+d.name(), err)
+		return
+	}
+	d.view.ScrollTo(scrollX, scrollY)
+	line, lineNum := d.text.LineByNum(lineNum)
+	d.text.SetCurLine(line, lineNum)
+}
+
+// askReload asks whether to reload a document with unsaved changes whose file has been changed, to merge
+// the changes of the file into it, or to keep it as it is. Then it calls then, if it is not nil, with whether
+// the user has chosen to keep the document.
+func (e *Editor) askReload(d *document, then func(kept bool)) {
+	e.activate(d)
+	dlg := gui.NewDialog(d.name()+" has been changed by another program.", "Reload", "Merge", "Keep mine")
+	dlg.OnChoose = func(choice int) {
+		kept := false
+		switch choice {
+		case 0: // Reload
+			e.reload(d)
+		case 1: // Merge
+			e.mergeFile(d)
+		default: // Keep mine, also on Escape; the file is overwritten on the next save
+			err := d.text.AcceptFile(d.fname)
+			if err != nil {
+				e.showError("Could not read "+d.name(), err)
+			} else {
+				kept = choice == 2
+			}
+		}
+		if then != nil {
+			then(kept)
+		}
+	}
+	gui.ShowModal(dlg)
+}
+
+// mergeFile merges the changes of the file into the document. Conflicts are marked in the text.
+func (e *Editor) mergeFile(d *document) {
+	conflicts, err := d.text.MergeFile(d.fname)
+	if err != nil {
+		e.showError("Could not merge "+d.name(), err)
+	} else if conflicts == 1 {
+		gui.ShowModal(gui.NewDialog("1 conflict is marked in the text.", "OK"))
+	} else if conflicts != 0 {
+		gui.ShowModal(gui.NewDialog(fmt.Sprintf("%d conflicts are marked in the text.", conflicts), "OK"))
+	}
+}
@@ -0,0 +1,179 @@
+package editor
+
+import (
+	"fmt"
+
+	"github.com/patrikaleksandryan/coloride/pkg/color"
+	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/text"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+type Searcher interface {
+	Find(query string, options text.SearchOptions) (count int, err error)
+	FindNext()
+	FindPrev()
+	Replace(replacement string)
+	ReplaceAll(replacement string) int
+	CountMatches() int
+	CloseSearch()
+}
+
+type Findbar struct {
+	gui.FrameImpl
+	searcher Searcher
+	options  text.SearchOptions
+
+	queryEdit   *gui.Edit
+	replaceEdit *gui.Edit
+	statusLabel *gui.Label
+
+	OnClose func()
+}
+
+func NewFindbar(searcher Searcher) *Findbar {
+	f := &Findbar{
+		searcher: searcher,
+	}
+	gui.InitFrame(&f.FrameImpl, 0, 0, 100, 20)
+
+	const gap = 4
+	const editW = 240
+	X, Y := 0, 4
+
+	label := gui.NewLabel("Find:", X, Y+4, 48, toolbarBtnH)
+	f.Append(label)
+	X += 48 + gap
+
+	f.queryEdit = gui.NewEdit(X, Y, editW, toolbarBtnH)
+	f.queryEdit.OnChange = f.find
+	f.queryEdit.OnEnter = f.findNext
+	f.queryEdit.OnEscape = f.close
+	f.Append(f.queryEdit)
+	X += editW + gap
+
+	f.appendToggleButton("Aa", X, Y, &f.options.CaseSensitive)
+	X += toolbarBtnH + 8 + gap
+	f.appendToggleButton("W", X, Y, &f.options.WholeWord)
+	X += toolbarBtnH + 8 + gap
+	f.appendToggleButton(".*", X, Y, &f.options.Regexp)
+	X += toolbarBtnH + 8 + gap
+
+	btn := gui.NewButton("<", X, Y, toolbarBtnH, toolbarBtnH)
+	btn.OnClick = func() { f.findNext(true) }
+	f.Append(btn)
+	X += toolbarBtnH + gap
+	btn = gui.NewButton(">", X, Y, toolbarBtnH, toolbarBtnH)
+	btn.OnClick = func() { f.findNext(false) }
+	f.Append(btn)
+	X += toolbarBtnH + 4*gap
+
+	label = gui.NewLabel("Replace:", X, Y+4, 72, toolbarBtnH)
+	f.Append(label)
+	X += 72 + gap
+
+	f.replaceEdit = gui.NewEdit(X, Y, editW, toolbarBtnH)
+	f.replaceEdit.OnEnter = func(shift bool) { f.replace() }
+	f.replaceEdit.OnEscape = f.close
+	f.Append(f.replaceEdit)
+	X += editW + gap
+
+	btn = gui.NewButton("Replace", X, Y, 80, toolbarBtnH)
+	btn.OnClick = f.replace
+	f.Append(btn)
+	X += 80 + gap
+	btn = gui.NewButton("All", X, Y, 48, toolbarBtnH)
+	btn.OnClick = f.replaceAll
+	f.Append(btn)
+	X += 48 + 4*gap
+
+	f.statusLabel = gui.NewLabel("", X, Y+4, 200, toolbarBtnH)
+	f.Append(f.statusLabel)
+
+	return f
+}
+
+// appendToggleButton appends a button that switches the given search option.
+func (f *Findbar) appendToggleButton(caption string, x, y int, option *bool) {
+	btn := gui.NewButton(caption, x, y, toolbarBtnH+8, toolbarBtnH)
+	btn.OnClick = func() {
+		*option = !*option
+		updateToggleButton(btn, *option)
+		f.find()
+	}
+	updateToggleButton(btn, *option)
+	f.Append(btn)
+}
+
+func updateToggleButton(btn *gui.Button, on bool) {
+	if on {
+		btn.SetColor(color.White)
+		btn.SetBgColor(color.MakeColor(113, 92, 72))
+	} else {
+		btn.SetColor(color.Black)
+		btn.SetBgColor(color.MakeColor(182, 150, 121))
+	}
+}
+
+// Open gives focus to the query field. The query is searched again, because the text may have changed.
+func (f *Findbar) Open() {
+	gui.SetFocus(f.queryEdit)
+	f.find()
+}
+
+func (f *Findbar) close() {
+	f.searcher.CloseSearch()
+	if f.OnClose != nil {
+		f.OnClose()
+	}
+}
+
+func (f *Findbar) find() {
+	count, err := f.searcher.Find(f.queryEdit.Text(), f.options)
+	if err != nil {
+		f.statusLabel.SetCaption("Invalid regexp")
+	} else {
+		f.updateCount(count)
+	}
+}
+
+func (f *Findbar) findNext(backward bool) {
+	if backward {
+		f.searcher.FindPrev()
+	} else {
+		f.searcher.FindNext()
+	}
+}
+
+func (f *Findbar) replace() {
+	f.searcher.Replace(f.replaceEdit.Text())
+	f.updateCount(f.searcher.CountMatches())
+}
+
+func (f *Findbar) replaceAll() {
+	count := f.searcher.ReplaceAll(f.replaceEdit.Text())
+	f.statusLabel.SetCaption(fmt.Sprintf("Replaced %d", count))
+}
+
+func (f *Findbar) updateCount(count int) {
+	switch {
+	case f.queryEdit.Text() == "":
+		f.statusLabel.SetCaption("")
+	case count == 0:
+		f.statusLabel.SetCaption("No matches")
+	case count == 1:
+		f.statusLabel.SetCaption("1 match")
+	default:
+		f.statusLabel.SetCaption(fmt.Sprintf("%d matches", count))
+	}
+}
+
+func (f *Findbar) Render(x, y int) {
+	w, h := f.Size()
+	rect := sdl.Rect{X: int32(x), Y: int32(y), W: int32(w), H: int32(h)}
+
+	gui.SetColor(f.BgColor())
+	gui.Renderer.FillRect(&rect)
+
+	f.RenderChildren(x, y)
+}
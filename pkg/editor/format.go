@@ -0,0 +1,51 @@
+package editor
+
+import (
+	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/scanner"
+	"github.com/patrikaleksandryan/coloride/pkg/text"
+)
+
+// updateFormat shows the encoding and the line endings of the active document, if they might have changed.
+func (e *Editor) updateFormat() {
+	d := e.doc
+	version := d.text.Version()
+	if e.formatUpdater == nil || d == e.formatDoc && version == e.formatVersion {
+		return
+	}
+	e.formatDoc, e.formatVersion = d, version
+
+	lineEnding := "Mixed"
+	if newLineType, mixed := d.text.NewLineStyle(); !mixed {
+		lineEnding = text.NewLineTypeName(newLineType)
+	}
+	e.formatUpdater.UpdateFormat(d.text.Encoding().String(), lineEnding)
+}
+
+// ChooseLineEnding asks for the line ending to convert the selected lines, or the whole document, to.
+func (e *Editor) ChooseLineEnding() {
+	if !e.editable() {
+		return
+	}
+	what := "the document"
+	if e.doc.text.HasSelection() {
+		what = "the selected lines"
+	}
+	newLineTypes := []int{scanner.LF, scanner.CRLF, scanner.CR}
+	dialog := gui.NewDialog("Convert line endings of "+what+" to:", "LF", "CRLF", "CR", "Cancel")
+	dialog.OnChoose = func(choice int) {
+		if 0 <= choice && choice < len(newLineTypes) {
+			e.ConvertNewLines(newLineTypes[choice])
+		}
+	}
+	gui.ShowModal(dialog)
+}
+
+// ConvertNewLines changes the line endings of the selected lines, or of the whole document, to newLineType.
+func (e *Editor) ConvertNewLines(newLineType int) {
+	if !e.editable() {
+		return
+	}
+	e.doc.text.ConvertNewLines(newLineType)
+	e.formatDoc = nil // The line ending of a single line is not a change of the text
+}
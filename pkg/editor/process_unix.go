@@ -0,0 +1,17 @@
+//go:build unix
+
+package editor
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processRunning reports whether a process with the ID pid is running.
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package editor
+
+import (
+	"errors"
+	"syscall"
+)
+
+const stillActive = 259 // Exit code of a process that has not exited yet
+
+// processRunning reports whether a process with the ID pid is running.
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	h, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	err = syscall.GetExitCodeProcess(h, &code)
+	return err != nil || code == stillActive
+}
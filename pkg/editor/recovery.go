@@ -0,0 +1,262 @@
+package editor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/patrikaleksandryan/coloride/pkg/atomicfile"
+	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/syntax"
+)
+
+const (
+	journalInterval = 5 * time.Second     // How often unsaved documents are journaled
+	journalStale    = 3 * journalInterval // Journals that cannot be read for so long are not being written anymore
+)
+
+// badJournalSuffix is appended to the names of journals that could not be restored.
+const badJournalSuffix = ".bad"
+
+// journalFile is the contents of a recovery journal of an unsaved document.
+type journalFile struct {
+	PID      int             `json:"pid"`      // Process ID of the editor that writes the journal
+	FileName string          `json:"fileName"` // "" if the document has never been saved
+	Text     json.RawMessage `json:"text"`     // See text.WriteJournal
+}
+
+// documentJournal is the state of the recovery journal of a document.
+type documentJournal struct {
+	fname string // Name of the journal file, "" if there is none
+	edits int    // Value of text.Edits when the journal was written
+}
+
+// recovery journals unsaved documents, so that they can be restored after the editor has crashed.
+type recovery struct {
+	dir     string    // Directory of journals, "" if there is no place for them
+	written time.Time // When the documents were journaled last
+	offered bool      // Whether the journals of earlier sessions have been offered
+	failed  bool      // Whether a failure to write a journal has been reported
+}
+
+// recoveryDir returns the directory of journals of the user.
+func recoveryDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "coloride", "recovery")
+}
+
+// updateJournals is called every frame. It offers to restore the journals of earlier sessions as soon as no
+// dialog is shown, and journals the unsaved documents every journalInterval.
+func (e *Editor) updateJournals() {
+	r := &e.recovery
+	if r.dir == "" {
+		return
+	}
+	if !r.offered && gui.Modal() == nil {
+		r.offered = true
+		e.offerRecovery()
+	}
+	if time.Since(r.written) < journalInterval {
+		return
+	}
+	r.written = time.Now()
+	for _, d := range e.docs {
+		e.writeJournal(d)
+	}
+}
+
+// writeJournal writes the journal of an unsaved document if the document has changed since the last time.
+func (e *Editor) writeJournal(d *document) {
+	if !d.edited {
+		d.removeJournal()
+		return
+	}
+	j := &d.journal
+	if j.fname != "" && j.edits == d.text.Edits() {
+		return
+	}
+
+	var buf bytes.Buffer
+	err := d.text.WriteJournal(&buf)
+	if err != nil {
+		e.journalFailed(err)
+		return
+	}
+	data, err := json.Marshal(journalFile{PID: os.Getpid(), FileName: d.fname, Text: buf.Bytes()})
+	if err != nil {
+		e.journalFailed(err)
+		return
+	}
+	if j.fname == "" {
+		j.fname, err = createJournal(e.recovery.dir)
+		if err != nil {
+			e.journalFailed(err)
+			return
+		}
+	}
+	err = atomicfile.WriteFile(j.fname, false, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		e.journalFailed(err)
+		return
+	}
+	j.edits = d.text.Edits()
+}
+
+// createJournal creates an empty journal file with a name that no other journal in dir has.
+func createJournal(dir string) (string, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, "*.json")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	return f.Name(), nil
+}
+
+// journalFailed reports the first failure to write a journal. Editing goes on without recovery.
+func (e *Editor) journalFailed(err error) {
+	if !e.recovery.failed {
+		e.recovery.failed = true
+		e.showError("Could not write the recovery journal, unsaved changes cannot be restored after a crash", err)
+	}
+}
+
+// removeJournal removes the journal of the document, which is saved, discarded or closed.
+func (d *document) removeJournal() {
+	if d.journal.fname != "" {
+		os.Remove(d.journal.fname)
+		d.journal.fname = ""
+	}
+}
+
+// removeJournals removes the journals of all documents when the editor is closed normally.
+func (e *Editor) removeJournals() {
+	for _, d := range e.docs {
+		d.removeJournal()
+	}
+}
+
+// staleJournals returns the journal files left by editors that are no longer running.
+func (e *Editor) staleJournals() []string {
+	entries, err := os.ReadDir(e.recovery.dir)
+	if err != nil {
+		return nil
+	}
+	var fnames []string
+	for _, entry := range entries {
+		fname := filepath.Join(e.recovery.dir, entry.Name())
+		if strings.HasSuffix(fname, ".json") && e.journalStale(fname) {
+			fnames = append(fnames, fname)
+		}
+	}
+	return fnames
+}
+
+// journalStale reports whether the journal file has been left by an editor that is no longer running.
+// A journal with the process ID of this editor is left by an earlier editor with the same ID, unless it
+// belongs to one of the documents. A journal that cannot be read may be just being created by another editor.
+func (e *Editor) journalStale(fname string) bool {
+	var jf struct {
+		PID int `json:"pid"`
+	}
+	data, err := os.ReadFile(fname)
+	if err == nil {
+		err = json.Unmarshal(data, &jf)
+	}
+	if err != nil || jf.PID == 0 {
+		info, err := os.Stat(fname)
+		return err == nil && time.Since(info.ModTime()) >= journalStale
+	}
+	if jf.PID == os.Getpid() {
+		for _, d := range e.docs {
+			if d.journal.fname == fname {
+				return false
+			}
+		}
+		return true
+	}
+	return !processRunning(jf.PID)
+}
+
+// offerRecovery asks whether to restore the documents left unsaved by earlier sessions.
+// If the user decides later, they are offered again on the next start.
+func (e *Editor) offerRecovery() {
+	fnames := e.staleJournals()
+	if len(fnames) == 0 {
+		return
+	}
+	message := "1 unsaved file of an earlier session can be restored."
+	if len(fnames) != 1 {
+		message = fmt.Sprintf("%d unsaved files of an earlier session can be restored.", len(fnames))
+	}
+	dlg := gui.NewDialog(message, "Restore", "Discard", "Later")
+	dlg.OnChoose = func(choice int) {
+		switch choice {
+		case 0: // Restore
+			for _, fname := range fnames {
+				e.restoreJournal(fname)
+			}
+		case 1: // Discard
+			for _, fname := range fnames {
+				os.Remove(fname)
+			}
+		}
+	}
+	gui.ShowModal(dlg)
+}
+
+// restoreJournal opens the document of the journal in a new tab as an unsaved document. The document gets
+// a journal of this editor, then the old one is removed. A journal that cannot be read is renamed with
+// badJournalSuffix, so that it is not offered again but the changes in it are not lost.
+func (e *Editor) restoreJournal(fname string) {
+	d := newDocument(e)
+	err := readJournal(d, fname)
+	if err != nil {
+		if os.Rename(fname, fname+badJournalSuffix) == nil {
+			fname += badJournalSuffix
+		}
+		e.showError("Could not restore the unsaved file kept in "+fname, err)
+		return
+	}
+	e.openDocument(d)
+	e.writeJournal(d)
+	os.Remove(fname)
+}
+
+func readJournal(d *document, fname string) error {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	var jf journalFile
+	err = json.Unmarshal(data, &jf)
+	if err != nil {
+		return err
+	}
+	err = d.text.ReadJournal(bytes.NewReader(jf.Text))
+	if err != nil {
+		return err
+	}
+	d.fname = jf.FileName
+	if d.fname != "" {
+		line, _ := d.text.LineByNum(1)
+		d.text.SetLexer(syntax.ForFile(d.fname, string(line.Chars())))
+		// The restored changes are compared with the file as it is now
+		d.text.AcceptFile(d.fname)
+	}
+	return nil
+}
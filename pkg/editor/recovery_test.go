@@ -0,0 +1,70 @@
+package editor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestStaleJournals(t *testing.T) {
+	dir := t.TempDir()
+	e := &Editor{recovery: recovery{dir: dir}}
+	write := func(name string, data []byte, age time.Duration) string {
+		fname := filepath.Join(dir, name)
+		err := os.WriteFile(fname, data, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Now().Add(-age)
+		err = os.Chtimes(fname, modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fname
+	}
+	journal := func(pid int) []byte {
+		data, err := json.Marshal(journalFile{PID: pid, Text: json.RawMessage("{}")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	// Journals of running editors are in use however long they have not been changed
+	write("running.json", journal(os.Getppid()), time.Hour)
+	own := write("own.json", journal(os.Getpid()), time.Hour)
+	e.docs = []*document{{journal: documentJournal{fname: own}}}
+	write("creating.json", nil, 0)
+	write("other.txt", nil, time.Hour)
+
+	want := []string{
+		write("exited.json", journal(1<<30), 0),
+		write("same-pid.json", journal(os.Getpid()), 0), // Left by an earlier editor with the same process ID
+		write("broken.json", []byte("{"), time.Hour),
+	}
+	got := e.staleJournals()
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stale journals are %v, want %v", got, want)
+	}
+}
+
+func TestCreateJournal(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "recovery")
+	names := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		fname, err := createJournal(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names[fname] {
+			t.Fatalf("%s is created twice", fname)
+		}
+		names[fname] = true
+	}
+}
@@ -0,0 +1,77 @@
+package editor
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/patrikaleksandryan/coloride/pkg/syntax"
+)
+
+const (
+	semanticDelay = 500 * time.Millisecond // Pause in typing before the text is analyzed again
+)
+
+// semanticAnalysis runs semantic analysis of a Go document in the background and feeds its results to the text.
+type semanticAnalysis struct {
+	running bool // Analysis is running in the background
+	results chan semanticResult
+	applied int       // Version of the text that has been analyzed, -1 if none
+	seen    int       // Last version of the text seen by updateSemantic
+	changed time.Time // When version seen has been seen first
+}
+
+type semanticResult struct {
+	version int
+	spans   []syntax.Span
+}
+
+func (d *document) initSemantic() {
+	d.semantic.results = make(chan semanticResult, 1)
+	d.semantic.applied = -1
+}
+
+// ToggleSemantic turns semantic highlighting on or off.
+func (e *Editor) ToggleSemantic() {
+	e.semantic = !e.semantic
+	if !e.semantic {
+		for _, d := range e.docs {
+			d.text.ClearSemantic()
+			d.semantic.applied = -1
+		}
+	}
+}
+
+// updateSemantic is called every frame. It takes the result of a finished analysis of the active document and
+// starts a new one when the text has not been changed for a while.
+func (e *Editor) updateSemantic() {
+	d := e.doc
+	s := &d.semantic
+	select {
+	case res := <-s.results:
+		s.running = false
+		if e.semantic && d.text.SetSemantic(res.version, res.spans) {
+			s.applied = res.version
+		}
+	default:
+	}
+
+	version := d.text.Version()
+	if version != s.seen {
+		s.seen = version
+		s.changed = time.Now()
+	}
+	if !e.semantic || s.running || version == s.applied || time.Since(s.changed) < semanticDelay ||
+		d.text.Lexer() != syntax.ByName("go") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if d.text.WriteUTF8(&buf) != nil {
+		return
+	}
+	s.running = true
+	results := s.results
+	go func() {
+		results <- semanticResult{version: version, spans: syntax.AnalyzeGo(buf.Bytes())}
+	}()
+}
@@ -1,12 +1,24 @@
 package editor
 
 import (
+	"github.com/patrikaleksandryan/coloride/pkg/color"
+	"github.com/patrikaleksandryan/coloride/pkg/colorcode"
 	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/palette"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+const (
+	legendTitleH = 32 // Height of the legend title
+	legendRowH   = 24 // Height of a legend entry
+	legendMarkW  = 32 // Width of the color mark of a legend entry
+)
+
+// Sidebar shows the legend: what each color of the palette means in the project.
 type Sidebar struct {
 	gui.FrameImpl
+
+	OnColorClick func(clr int) // Called when a legend entry is clicked
 }
 
 func NewSidebar() *Sidebar {
@@ -15,12 +27,64 @@ func NewSidebar() *Sidebar {
 	return s
 }
 
+// colorAt returns the color of the legend entry at y, or 0 if there is none.
+func (s *Sidebar) colorAt(y int) int {
+	if y < legendTitleH {
+		return 0
+	}
+	clr := (y-legendTitleH)/legendRowH + 1
+	if clr >= palette.Count() {
+		return 0
+	}
+	return clr
+}
+
+func (s *Sidebar) MouseDown(x, y, button int) {
+	clr := s.colorAt(y)
+	if clr != 0 && button == 1 && s.OnColorClick != nil {
+		s.OnColorClick(clr)
+	}
+}
+
+// legendCaption returns the meaning of the color, or its name if the meaning is not set.
+func legendCaption(e *palette.Entry) string {
+	if e.Meaning != "" {
+		return e.Meaning
+	}
+	return e.Name
+}
+
 func (s *Sidebar) Render(x, y int) {
 	w, h := s.Size()
+	_, charH := gui.FontSize()
 	rect := sdl.Rect{X: int32(x), Y: int32(y), W: int32(w), H: int32(h)}
 
 	gui.SetColor(s.BgColor())
 	gui.Renderer.FillRect(&rect)
 
+	const gap = 8
+	gui.Print("Legend", x+gap, y+(legendTitleH-charH)/2, color.Black, color.Transparent)
+
+	Y := y + legendTitleH
+	for clr := 1; clr < palette.Count(); clr++ {
+		e := palette.Get(clr)
+		fgColor, bgColor := markColors(clr)
+		markRect := sdl.Rect{X: int32(x + gap), Y: int32(Y + 2), W: legendMarkW, H: legendRowH - 4}
+		gui.SetColor(bgColor)
+		gui.Renderer.FillRect(&markRect)
+		code := colorcode.Code(clr)
+		if e.Letter == 0 {
+			code = ""
+		}
+		gui.PrintCentered(code, x+gap, Y, legendMarkW, legendRowH, fgColor, color.Transparent)
+
+		captionColor := color.Black
+		if e.Meaning == "" {
+			captionColor = s.Color()
+		}
+		gui.Print(legendCaption(e), x+2*gap+legendMarkW, Y+(legendRowH-charH)/2, captionColor, color.Transparent)
+		Y += legendRowH
+	}
+
 	s.RenderChildren(x, y)
 }
@@ -7,9 +7,16 @@ import (
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+// FormatUpdater shows the encoding and the line endings of the active document.
+type FormatUpdater interface {
+	UpdateFormat(encoding, lineEnding string)
+}
+
 type Statusbar struct {
 	gui.FrameImpl
-	PositionLabel *gui.Label
+	PositionLabel    *gui.Label
+	EncodingLabel    *gui.Label
+	LineEndingButton *gui.Button
 }
 
 func NewStatusbar() *Statusbar {
@@ -20,18 +27,35 @@ func NewStatusbar() *Statusbar {
 	s.PositionLabel.SetAlign(gui.AlignRight)
 	s.Append(s.PositionLabel)
 
+	s.EncodingLabel = gui.NewLabel("UTF-8", 0, 8, 120, 32)
+	s.EncodingLabel.SetAlign(gui.AlignRight)
+	s.Append(s.EncodingLabel)
+
+	s.LineEndingButton = gui.NewButton("LF", 0, 4, 80, 32)
+	s.Append(s.LineEndingButton)
+
 	return s
 }
 
+func (s *Statusbar) UpdateFormat(encoding, lineEnding string) {
+	s.EncodingLabel.SetCaption(encoding)
+	s.LineEndingButton.SetCaption(lineEnding)
+}
+
 func (s *Statusbar) UpdatePos(line, col int) {
 	s.PositionLabel.SetCaption(fmt.Sprintf("%d:%d", line, col))
 }
 
 func (s *Statusbar) ResizeInside() {
-	_, y := s.PositionLabel.Pos()
-	w, _ := s.PositionLabel.Size()
-	W, _ := s.Size()
-	s.PositionLabel.SetPos(W-w, y)
+	const gap = 16
+	X, _ := s.Size()
+	for _, f := range []gui.Frame{s.PositionLabel, s.LineEndingButton, s.EncodingLabel} {
+		_, y := f.Pos()
+		w, _ := f.Size()
+		X -= w
+		f.SetPos(X, y)
+		X -= gap
+	}
 }
 
 func (s *Statusbar) Render(x, y int) {
@@ -0,0 +1,93 @@
+package editor
+
+import (
+	"github.com/patrikaleksandryan/coloride/pkg/color"
+	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/theme"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	tabMinW = 120 // Minimal width of a tab
+	tabPadW = 16  // Space around the caption of a tab
+	tabGap  = 4   // Space between tabs
+)
+
+// TabsUpdater is notified when the set of open documents or their captions change.
+type TabsUpdater interface {
+	UpdateTabs(captions []string, active int)
+}
+
+// Tabbar shows a tab for every open document. A click selects the tab, a middle click closes it.
+type Tabbar struct {
+	gui.FrameImpl
+
+	captions []string
+	active   int
+
+	OnSelect func(i int) // Called when a tab is clicked
+	OnClose  func(i int) // Called when a tab is clicked with the middle button
+}
+
+func NewTabbar() *Tabbar {
+	t := &Tabbar{}
+	gui.InitFrame(&t.FrameImpl, 0, 0, 100, 20)
+	return t
+}
+
+func (t *Tabbar) UpdateTabs(captions []string, active int) {
+	t.captions = captions
+	t.active = active
+}
+
+// tabWidth returns the width of the tab with the given caption.
+func tabWidth(caption string) int {
+	charW, _ := gui.FontSize()
+	return max(tabMinW, len([]rune(caption))*charW+2*tabPadW)
+}
+
+// tabAt returns the index of the tab at x, or -1 if there is none.
+func (t *Tabbar) tabAt(x int) int {
+	X := 0
+	for i, caption := range t.captions {
+		w := tabWidth(caption)
+		if X <= x && x < X+w {
+			return i
+		}
+		X += w + tabGap
+	}
+	return -1
+}
+
+func (t *Tabbar) MouseDown(x, y, button int) {
+	i := t.tabAt(x)
+	if i == -1 {
+		return
+	}
+	if button == 1 && t.OnSelect != nil {
+		t.OnSelect(i)
+	} else if button == 2 && t.OnClose != nil {
+		t.OnClose(i)
+	}
+}
+
+func (t *Tabbar) Render(x, y int) {
+	w, h := t.Size()
+	gui.SetColor(t.BgColor())
+	gui.Renderer.FillRect(&sdl.Rect{X: int32(x), Y: int32(y), W: int32(w), H: int32(h)})
+
+	X := x
+	for i, caption := range t.captions {
+		tabW := tabWidth(caption)
+		bgColor, textColor := t.Color(), color.White
+		if i == t.active {
+			bgColor, textColor = theme.Chrome.Background, theme.Chrome.CurLineNumber
+		}
+		gui.SetColor(bgColor)
+		gui.Renderer.FillRect(&sdl.Rect{X: int32(X), Y: int32(y + 4), W: int32(tabW), H: int32(h - 4)})
+		gui.PrintCentered(caption, X, y+4, tabW, h-4, textColor, color.Transparent)
+		X += tabW + tabGap
+	}
+
+	t.RenderChildren(x, y)
+}
@@ -5,12 +5,11 @@ import (
 
 	"github.com/patrikaleksandryan/coloride/pkg/color"
 	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/palette"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 const (
-	colorCount = 9
-
 	toolbarBtnH = 32 // Height of toolbar buttons
 )
 
@@ -18,6 +17,11 @@ type Colorizer interface {
 	ColorizeSelection(color int)
 }
 
+type RunNavigator interface {
+	NextColorRun(backward bool)
+	SetSameColorRuns(on bool)
+}
+
 type FileManager interface {
 	NewFile()
 	OpenFile()
@@ -29,18 +33,22 @@ type Toolbar struct {
 	gui.FrameImpl
 	fileManager FileManager
 	colorizer   Colorizer
+	navigator   RunNavigator
 
-	colorButtons [colorCount]*gui.Button
+	colorButtons  []*gui.Button
+	colorButtonsX int // Position of the first color button
 }
 
-func NewToolbar(fileManager FileManager, colorizer Colorizer) *Toolbar {
+func NewToolbar(fileManager FileManager, colorizer Colorizer, navigator RunNavigator) *Toolbar {
 	t := &Toolbar{
 		fileManager: fileManager,
 		colorizer:   colorizer,
+		navigator:   navigator,
 	}
 	gui.InitFrame(&t.FrameImpl, 0, 0, 100, 20)
 	x := t.initFileButtons()
-	t.initColorButtons(x)
+	t.colorButtonsX = t.initRunButtons(x)
+	t.initColorButtons()
 	return t
 }
 
@@ -74,10 +82,40 @@ func (t *Toolbar) initFileButtons() (X int) {
 	return
 }
 
-func (t *Toolbar) initColorButtons(X int) {
+// initRunButtons appends buttons that move between colored regions.
+func (t *Toolbar) initRunButtons(X int) int {
+	const gap = 4
+	sameColor := false
+
+	btn := gui.NewButton("<", X, 0, toolbarBtnH, toolbarBtnH)
+	btn.OnClick = func() { t.navigator.NextColorRun(true) }
+	t.Append(btn)
+	X += toolbarBtnH + gap
+
+	sameBtn := gui.NewButton("=", X, 0, toolbarBtnH, toolbarBtnH)
+	sameBtn.OnClick = func() {
+		sameColor = !sameColor
+		t.navigator.SetSameColorRuns(sameColor)
+		updateToggleButton(sameBtn, sameColor)
+	}
+	updateToggleButton(sameBtn, sameColor)
+	t.Append(sameBtn)
+	X += toolbarBtnH + gap
+
+	btn = gui.NewButton(">", X, 0, toolbarBtnH, toolbarBtnH)
+	btn.OnClick = func() { t.navigator.NextColorRun(false) }
+	t.Append(btn)
+	X += toolbarBtnH + 4*gap
+
+	return X
+}
+
+// initColorButtons appends a button for every color of the palette.
+func (t *Toolbar) initColorButtons() {
 	const gap = 4
-	Y := 0
-	for i := 0; i < colorCount; i++ {
+	X, Y := t.colorButtonsX, 0
+	t.colorButtons = make([]*gui.Button, palette.Count())
+	for i := 0; i < palette.Count(); i++ {
 		caption := fmt.Sprintf("%d", i)
 		btn := gui.NewButton(caption, X, Y, toolbarBtnH, toolbarBtnH)
 		fgColor, bgColor := t.ButtonColorByNum(i)
@@ -92,30 +130,28 @@ func (t *Toolbar) initColorButtons(X int) {
 	}
 }
 
+// UpdateColors recreates the color buttons after the palette has changed.
+func (t *Toolbar) UpdateColors() {
+	for _, btn := range t.colorButtons {
+		t.Remove(btn)
+	}
+	t.initColorButtons()
+}
+
 func (t *Toolbar) ButtonColorByNum(i int) (clr color.Color, bgColor color.Color) {
-	switch i {
-	case 0:
-		return color.White, color.Black
+	return markColors(i)
+}
 
-	case 1:
-		return color.MakeColor(200, 20, 20), color.MakeColor(50, 25, 25)
-	case 2:
-		return color.MakeColor(0, 170, 0), color.MakeColor(25, 50, 25)
-	case 3:
-		return color.MakeColor(0, 50, 230), color.MakeColor(30, 30, 70)
-	case 4:
-		return color.MakeColor(200, 200, 0), color.MakeColor(50, 50, 25)
-
-	case 5:
-		return color.White, color.MakeColor(200, 0, 0)
-	case 6:
-		return color.White, color.MakeColor(0, 170, 0)
-	case 7:
-		return color.White, color.MakeColor(0, 20, 200)
-	case 8:
-		return color.Black, color.MakeColor(240, 230, 0)
+// markColors returns the colors that represent the palette color i on buttons and in the legend.
+func markColors(i int) (clr color.Color, bgColor color.Color) {
+	if i == 0 {
+		return color.White, color.Black
+	}
+	e := palette.Get(i)
+	if e.OverrideColor {
+		return e.Color, e.BgColor
 	}
-	return color.Black, color.Black // Impossible
+	return e.Mark, e.BgColor
 }
 
 func (t *Toolbar) Render(x, y int) {
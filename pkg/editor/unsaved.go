@@ -0,0 +1,39 @@
+package editor
+
+// unsavedChanges asks what to do with the unsaved changes of documents before they are closed.
+// The questions are shown and the documents are saved by the functions it is given, so that it does not
+// depend on the window.
+type unsavedChanges struct {
+	ask  func(d *document, answer func(choice int)) // Asks whether to save d, answer gets the index of the button or -1
+	save func(d *document, done func())             // Saves d and calls done, also if saving has failed
+}
+
+// confirm asks whether to save the changes of the document, if it has any. Then it calls next,
+// unless the user cancels or the document could not be saved.
+func (u unsavedChanges) confirm(d *document, next func()) {
+	if !d.edited {
+		next()
+		return
+	}
+	u.ask(d, func(choice int) {
+		switch choice {
+		case 0: // Save
+			u.save(d, func() {
+				if !d.edited {
+					next()
+				}
+			})
+		case 1: // Discard
+			next()
+		}
+	})
+}
+
+// confirmAll calls confirm for every document in turn, and then next.
+func (u unsavedChanges) confirmAll(docs []*document, next func()) {
+	if len(docs) == 0 {
+		next()
+		return
+	}
+	u.confirm(docs[0], func() { u.confirmAll(docs[1:], next) })
+}
@@ -0,0 +1,131 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+)
+
+// scriptedUser answers the questions about unsaved changes with the given choices and records them.
+type scriptedUser struct {
+	choices  []int
+	asked    []string        // Names of the documents asked about
+	saved    []string        // Names of the documents saved
+	failSave map[string]bool // Documents that cannot be saved
+}
+
+func (s *scriptedUser) unsavedChanges() unsavedChanges {
+	return unsavedChanges{
+		ask: func(d *document, answer func(choice int)) {
+			s.asked = append(s.asked, d.fname)
+			choice := s.choices[0]
+			s.choices = s.choices[1:]
+			answer(choice)
+		},
+		save: func(d *document, done func()) {
+			s.saved = append(s.saved, d.fname)
+			if !s.failSave[d.fname] {
+				d.edited = false
+			}
+			done()
+		},
+	}
+}
+
+func TestConfirmCloseTab(t *testing.T) {
+	tests := []struct {
+		name     string
+		edited   bool
+		choice   int
+		failSave bool
+		closed   bool
+		saved    bool
+	}{
+		{"unchanged", false, -1, false, true, false},
+		{"save", true, 0, false, true, true},
+		{"save fails", true, 0, true, false, true},
+		{"discard", true, 1, false, true, false},
+		{"cancel", true, 2, false, false, false},
+		{"escape", true, -1, false, false, false},
+	}
+	for _, tt := range tests {
+		user := &scriptedUser{choices: []int{tt.choice}, failSave: map[string]bool{"a.go": tt.failSave}}
+		d := &document{fname: "a.go", edited: tt.edited}
+		closed := false
+		user.unsavedChanges().confirm(d, func() { closed = true })
+		if closed != tt.closed {
+			t.Errorf("%s: closed = %v, want %v", tt.name, closed, tt.closed)
+		}
+		if saved := len(user.saved) != 0; saved != tt.saved {
+			t.Errorf("%s: saved = %v, want %v", tt.name, saved, tt.saved)
+		}
+		if asked := len(user.asked) != 0; asked != tt.edited {
+			t.Errorf("%s: asked = %v, want %v", tt.name, asked, tt.edited)
+		}
+	}
+}
+
+func TestConfirmQuit(t *testing.T) {
+	tests := []struct {
+		name     string
+		choices  []int
+		failSave string
+		quit     bool
+		asked    []string
+		saved    []string
+	}{
+		{"save all", []int{0, 0}, "", true, []string{"a.go", "c.go"}, []string{"a.go", "c.go"}},
+		{"discard all", []int{1, 1}, "", true, []string{"a.go", "c.go"}, nil},
+		{"save and discard", []int{0, 1}, "", true, []string{"a.go", "c.go"}, []string{"a.go"}},
+		{"cancel first", []int{2}, "", false, []string{"a.go"}, nil},
+		{"cancel second", []int{1, -1}, "", false, []string{"a.go", "c.go"}, nil},
+		{"save fails", []int{0}, "a.go", false, []string{"a.go"}, []string{"a.go"}},
+	}
+	for _, tt := range tests {
+		user := &scriptedUser{choices: tt.choices, failSave: map[string]bool{tt.failSave: true}}
+		docs := []*document{
+			{fname: "a.go", edited: true},
+			{fname: "b.go"},
+			{fname: "c.go", edited: true},
+		}
+		quit := false
+		user.unsavedChanges().confirmAll(docs, func() { quit = true })
+		if quit != tt.quit {
+			t.Errorf("%s: quit = %v, want %v", tt.name, quit, tt.quit)
+		}
+		if !reflect.DeepEqual(user.asked, tt.asked) {
+			t.Errorf("%s: asked about %v, want %v", tt.name, user.asked, tt.asked)
+		}
+		if !reflect.DeepEqual(user.saved, tt.saved) {
+			t.Errorf("%s: saved %v, want %v", tt.name, user.saved, tt.saved)
+		}
+	}
+}
+
+// TestConfirmQuitWaitsForSave checks that quitting goes on when a document is saved after another question,
+// as when its file has been changed by another program.
+func TestConfirmQuitWaitsForSave(t *testing.T) {
+	var saveDone func()
+	u := unsavedChanges{
+		ask: func(d *document, answer func(choice int)) { answer(0) },
+		save: func(d *document, done func()) {
+			saveDone = func() {
+				d.edited = false
+				done()
+			}
+		},
+	}
+	docs := []*document{{fname: "a.go", edited: true}, {fname: "b.go", edited: true}}
+	quit := false
+	u.confirmAll(docs, func() { quit = true })
+	for i := 0; i < len(docs); i++ {
+		if quit || saveDone == nil {
+			t.Fatalf("document %d: quit = %v before it has been saved", i, quit)
+		}
+		done := saveDone
+		saveDone = nil
+		done()
+	}
+	if !quit {
+		t.Error("the editor does not quit after the documents have been saved")
+	}
+}
@@ -1,7 +1,10 @@
 package editor
 
 import (
+	"path/filepath"
+
 	"github.com/patrikaleksandryan/coloride/pkg/gui"
+	"github.com/patrikaleksandryan/coloride/pkg/theme"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
@@ -11,7 +14,12 @@ type Window struct {
 	menu      *Menu
 	statusbar *Statusbar
 	toolbar   *Toolbar
+	findbar   *Findbar
+	sidebar   *Sidebar
+	tabbar    *Tabbar
 	editor    *Editor
+
+	themeWatcher theme.Watcher
 }
 
 func NewWindow() *Window {
@@ -21,14 +29,37 @@ func NewWindow() *Window {
 
 	win.menu = NewMenu()
 	win.statusbar = NewStatusbar()
-	win.editor = NewEditor(win.menu, win.menu, win.statusbar)
-	win.toolbar = NewToolbar(win.editor, win.editor)
+	win.tabbar = NewTabbar()
+	win.editor = NewEditor(win.menu, win.menu, win.statusbar, win.tabbar, win.statusbar)
+	win.statusbar.LineEndingButton.OnClick = win.editor.ChooseLineEnding
+	win.tabbar.OnSelect = func(i int) {
+		win.editor.SelectTab(i)
+		gui.SetFocus(win.editor)
+	}
+	win.tabbar.OnClose = win.editor.CloseTab
+	win.toolbar = NewToolbar(win.editor, win.editor, win.editor)
+	win.findbar = NewFindbar(win.editor)
+	win.findbar.SetVisible(false)
+	win.findbar.OnClose = win.closeFindbar
+	win.editor.OnFind = win.openFindbar
+	win.sidebar = NewSidebar()
+	win.sidebar.OnColorClick = func(clr int) {
+		win.editor.FindColorRun(clr)
+		gui.SetFocus(win.editor)
+	}
 
 	win.Append(win.menu)
 	win.Append(win.statusbar)
 	win.Append(win.toolbar)
+	win.Append(win.findbar)
+	win.Append(win.sidebar)
+	win.Append(win.tabbar)
 	win.Append(win.editor)
 
+	win.checkTheme()
+	gui.OnTick(win.checkTheme)
+	gui.OnQuit(func() { win.editor.ConfirmQuit(gui.Quit) })
+
 	return win
 }
 
@@ -37,15 +68,54 @@ func (win *Window) ResizeInside() {
 	const menuH = 40
 	const statusbarH = 40
 	const toolbarH = 40
+	const findbarH = 40
+	const legendW = 240
+	const legendGap = 8
+	const tabbarH = 36
 
 	frame := 16
 	X, Y, W, H := frame, frame, w-2*frame, h-2*frame
 	sidebarH := H - menuH - statusbarH
+	if win.findbar.Visible() {
+		sidebarH -= findbarH
+		gui.SetGeometry(win.findbar, X, Y+H-statusbarH-findbarH, W, findbarH)
+	}
 
 	gui.SetGeometry(win.menu, X, Y, W, menuH)
 	gui.SetGeometry(win.statusbar, X, Y+H-statusbarH, W, statusbarH)
 	gui.SetGeometry(win.toolbar, X, Y+menuH, W, toolbarH)
-	gui.SetGeometry(win.editor, X, Y+menuH+toolbarH, W, sidebarH-toolbarH)
+	gui.SetGeometry(win.sidebar, X, Y+menuH+toolbarH, legendW, sidebarH-toolbarH)
+	gui.SetGeometry(win.tabbar, X+legendW+legendGap, Y+menuH+toolbarH, W-legendW-legendGap, tabbarH)
+	gui.SetGeometry(win.editor, X+legendW+legendGap, Y+menuH+toolbarH+tabbarH, W-legendW-legendGap,
+		sidebarH-toolbarH-tabbarH)
+}
+
+func (win *Window) openFindbar() {
+	if !win.findbar.Visible() {
+		win.findbar.SetVisible(true)
+		win.ResizeInside()
+	}
+	win.findbar.Open()
+}
+
+func (win *Window) closeFindbar() {
+	win.findbar.SetVisible(false)
+	win.ResizeInside()
+	gui.SetFocus(win.editor)
+}
+
+// checkTheme reloads the project configuration when it changes or when a file from another project is opened.
+func (win *Window) checkTheme() {
+	dir := "."
+	if fname := win.editor.FileName(); fname != "" {
+		dir = filepath.Dir(fname)
+	}
+	changed, err := win.themeWatcher.Check(dir)
+	if err != nil {
+		win.editor.showError("Could not load the configuration", err)
+	} else if changed {
+		win.toolbar.UpdateColors()
+	}
 }
 
 func (win *Window) Render(x, y int) {
@@ -0,0 +1,16 @@
+package gui
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Clipboard is the system clipboard, accessed through SDL.
+type Clipboard struct{}
+
+func (c Clipboard) SetText(text string) error {
+	return sdl.SetClipboardText(text)
+}
+
+func (c Clipboard) Text() (string, error) {
+	return sdl.GetClipboardText()
+}
@@ -0,0 +1,114 @@
+package gui
+
+import (
+	"strings"
+
+	"github.com/patrikaleksandryan/coloride/pkg/color"
+	"github.com/patrikaleksandryan/coloride/pkg/text"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	dialogPad  = 16  // Space around the message and the buttons
+	dialogBtnW = 120 // Width of dialog buttons
+	dialogBtnH = 32  // Height of dialog buttons
+	dialogGap  = 8   // Space between buttons
+	dialogMaxW = 800 // Width of dialogs, unless the window is narrower; longer messages are wrapped
+)
+
+// Dialog is a modal window with a message and a row of buttons, see ShowModal.
+// Enter chooses the first button, Escape cancels the dialog.
+type Dialog struct {
+	FrameImpl
+	lines []string // Lines of the wrapped message
+
+	OnChoose func(choice int) // Called with the index of the chosen button, or -1 if the dialog was cancelled
+}
+
+func NewDialog(message string, captions ...string) *Dialog {
+	d := &Dialog{}
+	btnsW := len(captions)*(dialogBtnW+dialogGap) - dialogGap
+	maxW := dialogMaxW
+	if mainFrame != nil {
+		if windowW, _ := mainFrame.Size(); windowW != 0 {
+			maxW = min(maxW, windowW-2*dialogPad)
+		}
+	}
+	d.lines = wrapText(message, (max(maxW, btnsW+2*dialogPad)-2*dialogPad)/charW)
+	textW := 0
+	for _, line := range d.lines {
+		textW = max(textW, len([]rune(line))*charW)
+	}
+	w := max(textW, btnsW) + 2*dialogPad
+	h := 3*dialogPad + len(d.lines)*charH + dialogBtnH
+	InitFrame(&d.FrameImpl, 0, 0, w, h)
+
+	X := w - dialogPad - btnsW
+	for i, caption := range captions {
+		btn := NewButton(caption, X, h-dialogPad-dialogBtnH, dialogBtnW, dialogBtnH)
+		btn.OnClick = func() { d.Choose(i) }
+		d.Append(btn)
+		X += dialogBtnW + dialogGap
+	}
+	return d
+}
+
+// Choose closes the dialog as if the button with the given index was pressed.
+func (d *Dialog) Choose(choice int) {
+	closeModal(d)
+	if d.OnChoose != nil {
+		d.OnChoose(choice)
+	}
+}
+
+func (d *Dialog) OnKeyDown(key int, mod uint16) {
+	if key == sdl.K_ESCAPE {
+		d.Choose(-1)
+	}
+}
+
+// OnCharInput handles Enter, so that the character is not passed to the frame that gets the focus back.
+func (d *Dialog) OnCharInput(r rune) {
+	if r == text.KeyEnter {
+		d.Choose(0)
+	}
+}
+
+func (d *Dialog) Render(x, y int) {
+	rect := sdl.Rect{X: int32(x), Y: int32(y), W: int32(d.w), H: int32(d.h)}
+	SetColor(d.bgColor)
+	Renderer.FillRect(&rect)
+	SetColor(color.MakeRGBA(113, 92, 72, 255))
+	Renderer.DrawRect(&rect)
+
+	for i, line := range d.lines {
+		Print(line, x+dialogPad, y+dialogPad+i*charH, color.Black, color.Transparent)
+	}
+	d.RenderChildren(x, y)
+}
+
+// wrapText splits s into lines of at most width characters, breaking them at spaces where possible.
+func wrapText(s string, width int) []string {
+	width = max(width, 1)
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := []rune{}
+		for _, word := range strings.Fields(paragraph) {
+			w := []rune(word)
+			if len(line) != 0 && len(line)+1+len(w) > width {
+				lines = append(lines, string(line))
+				line = line[:0]
+			}
+			if len(line) != 0 {
+				line = append(line, ' ')
+			}
+			line = append(line, w...)
+			for len(line) > width {
+				lines = append(lines, string(line[:width]))
+				line = append(line[:0], line[width:]...)
+			}
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
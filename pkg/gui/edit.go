@@ -0,0 +1,124 @@
+package gui
+
+import (
+	"github.com/patrikaleksandryan/coloride/pkg/color"
+	"github.com/patrikaleksandryan/coloride/pkg/text"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Edit is a single-line text input field.
+type Edit struct {
+	FrameImpl
+	chars  []rune
+	cursor int // 0-based position of the cursor in chars
+
+	OnChange func()
+	OnEnter  func(shift bool)
+	OnEscape func()
+}
+
+func NewEdit(x, y, w, h int) *Edit {
+	e := &Edit{}
+	InitFrame(&e.FrameImpl, x, y, w, h)
+	e.color = color.Black
+	e.bgColor = color.MakeColor(235, 235, 207)
+	return e
+}
+
+func (e *Edit) Text() string {
+	return string(e.chars)
+}
+
+func (e *Edit) SetText(s string) {
+	e.chars = []rune(s)
+	e.cursor = len(e.chars)
+}
+
+func (e *Edit) changed() {
+	if e.OnChange != nil {
+		e.OnChange()
+	}
+}
+
+func (e *Edit) OnCharInput(r rune) {
+	switch r {
+	case text.KeyBackspace:
+		if e.cursor != 0 {
+			e.chars = append(e.chars[:e.cursor-1], e.chars[e.cursor:]...)
+			e.cursor--
+			e.changed()
+		}
+	case text.KeyDelete:
+		if e.cursor != len(e.chars) {
+			e.chars = append(e.chars[:e.cursor], e.chars[e.cursor+1:]...)
+			e.changed()
+		}
+	case text.KeyEnter, text.KeyTab: // Enter is handled in OnKeyDown
+	default:
+		e.chars = append(e.chars, 0)
+		copy(e.chars[e.cursor+1:], e.chars[e.cursor:])
+		e.chars[e.cursor] = r
+		e.cursor++
+		e.changed()
+	}
+}
+
+func (e *Edit) OnKeyDown(key int, mod uint16) {
+	switch key {
+	case sdl.K_LEFT:
+		if e.cursor != 0 {
+			e.cursor--
+		}
+	case sdl.K_RIGHT:
+		if e.cursor != len(e.chars) {
+			e.cursor++
+		}
+	case sdl.K_HOME:
+		e.cursor = 0
+	case sdl.K_END:
+		e.cursor = len(e.chars)
+	case sdl.K_RETURN, sdl.K_KP_ENTER:
+		if e.OnEnter != nil {
+			e.OnEnter(mod&sdl.KMOD_SHIFT != 0)
+		}
+	case sdl.K_ESCAPE:
+		if e.OnEscape != nil {
+			e.OnEscape()
+		}
+	}
+}
+
+func (e *Edit) MouseDown(x, y, button int) {
+	SetFocus(e)
+	e.FrameImpl.MouseDown(x, y, button)
+}
+
+func (e *Edit) Render(x, y int) {
+	rect := sdl.Rect{X: int32(x), Y: int32(y), W: int32(e.w), H: int32(e.h)}
+	SetColor(e.bgColor)
+	Renderer.FillRect(&rect)
+
+	charW, charH := FontSize()
+	const padding = 4
+	textY := y + (e.h-charH)/2
+
+	// Scroll the text to the left if the cursor does not fit
+	first := 0
+	visible := (e.w - 2*padding) / charW
+	if e.cursor >= visible {
+		first = e.cursor - visible + 1
+	}
+	X := x + padding
+	for i := first; i < len(e.chars) && i-first < visible; i++ {
+		PrintChar(e.chars[i], X, textY, e.color, color.Transparent)
+		X += charW
+	}
+
+	if e.focused {
+		SetColor(e.color)
+		cursorX := x + padding + (e.cursor-first)*charW
+		Renderer.FillRect(&sdl.Rect{X: int32(cursorX), Y: int32(textY), W: 2, H: int32(charH)})
+	}
+
+	e.RenderChildren(x, y)
+}
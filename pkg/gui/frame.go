@@ -45,6 +45,7 @@ type Frame interface {
 	Render(x, y int)
 
 	Append(child Frame)
+	Remove(child Frame)
 	HasChildren() bool
 	FirstChild() Frame
 	Prev() Frame
@@ -112,6 +113,14 @@ func (f *FrameImpl) Append(child Frame) {
 	f.body.SetPrev(child)
 }
 
+// Remove removes child from the ring of children of f.
+func (f *FrameImpl) Remove(child Frame) {
+	child.Prev().SetNext(child.Next())
+	child.Next().SetPrev(child.Prev())
+	child.SetPrev(nil)
+	child.SetNext(nil)
+}
+
 func (f *FrameImpl) Pos() (x, y int) {
 	return f.x, f.y
 }
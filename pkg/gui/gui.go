@@ -2,6 +2,8 @@ package gui
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
 
 	"github.com/patrikaleksandryan/coloride/pkg/font"
@@ -30,6 +32,14 @@ var (
 
 	lastMouseX, lastMouseY int // For mouse wheel event, because MouseX and MouseY are not available
 
+	tickers       []func() // Called once per frame
+	focusHandlers []func() // Called when the window gets the focus
+
+	modals []modalFrame // Frames shown by ShowModal, the last one gets all input
+
+	quitHandler func() // Called when the window is closed, see OnQuit
+	quitting    bool
+
 	IsMacOS bool
 )
 
@@ -48,6 +58,85 @@ func SetFocus(frame Frame) {
 	}
 }
 
+// OnTick registers f to be called once per frame before rendering, i.e. to poll for changes of files.
+func OnTick(f func()) {
+	tickers = append(tickers, f)
+}
+
+// OnWindowFocus registers f to be called when the window gets the focus, i.e. to check for files changed
+// by other programs.
+func OnWindowFocus(f func()) {
+	focusHandlers = append(focusHandlers, f)
+}
+
+// OnQuit registers f to be called when the user closes the window. The application keeps running until f,
+// or anything it starts, calls Quit.
+func OnQuit(f func()) {
+	quitHandler = f
+}
+
+// Quit makes Run return after the current frame.
+func Quit() {
+	quitting = true
+}
+
+// modalFrame is a frame shown by ShowModal.
+type modalFrame struct {
+	frame Frame
+	focus Frame // Focused frame before the frame was shown
+}
+
+// ShowModal shows the frame centered over the window. Until CloseModal is called, the frame gets the focus
+// and all mouse events. A frame shown while another one is shown is put over it, and the other one
+// gets the focus back when the frame is closed.
+func ShowModal(frame Frame) {
+	centerModal(frame)
+	modals = append(modals, modalFrame{frame: frame, focus: focusFrame})
+	SetFocus(frame)
+}
+
+// centerModal puts the modal frame in the middle of the window.
+func centerModal(frame Frame) {
+	if mainFrame != nil {
+		w, h := mainFrame.Size()
+		fw, fh := frame.Size()
+		SetGeometry(frame, (w-fw)/2, (h-fh)/2, fw, fh)
+	}
+}
+
+// CloseModal hides the last modal frame and gives the focus back.
+func CloseModal() {
+	if len(modals) != 0 {
+		closeModal(modals[len(modals)-1].frame)
+	}
+}
+
+// closeModal hides the modal frame. If other frames have been shown after it, the one shown right after it
+// gives the focus back to the frame that had it before the closed one.
+func closeModal(frame Frame) {
+	for i := len(modals) - 1; i >= 0; i-- {
+		if modals[i].frame != frame {
+			continue
+		}
+		focus := modals[i].focus
+		modals = append(modals[:i], modals[i+1:]...)
+		if i == len(modals) {
+			SetFocus(focus)
+		} else {
+			modals[i].focus = focus
+		}
+		return
+	}
+}
+
+// Modal returns the last frame shown by ShowModal, or nil.
+func Modal() Frame {
+	if len(modals) == 0 {
+		return nil
+	}
+	return modals[len(modals)-1].frame
+}
+
 func SetWindowTitle(title string) {
 	window.SetTitle(title)
 }
@@ -74,7 +163,7 @@ func Init(windowWidth, windowHeight int) error {
 		return fmt.Errorf("could not create Renderer: %v", err)
 	}
 
-	mainFont, err = font.Open(Renderer, "data/fonts/main.ttf", fontSize, charW, charH)
+	mainFont, err = font.Open(Renderer, dataFile("data/fonts/main.ttf"), fontSize, charW, charH)
 	if err != nil {
 		return fmt.Errorf("could not open font: %v", err)
 	}
@@ -86,6 +175,23 @@ func Init(windowWidth, windowHeight int) error {
 	return nil
 }
 
+// dataFile returns the path of a file shipped with ColorIDE. It is looked for in the working directory first,
+// then next to the executable, so that the editor can be started from any directory.
+func dataFile(name string) string {
+	if _, err := os.Stat(name); err == nil {
+		return name
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return name
+	}
+	path := filepath.Join(filepath.Dir(exe), name)
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return name
+}
+
 func Close() {
 	if mainFont != nil {
 		mainFont.Close()
@@ -110,10 +216,16 @@ func ResizeMainFrame(w, h int) {
 				SetGeometry(child, 0, 0, w, h)
 			}
 		}
+		for _, m := range modals {
+			centerModal(m.frame)
+		}
 	}
 }
 
 func handleMouseWheel(e *sdl.MouseWheelEvent) {
+	if Modal() != nil {
+		return
+	}
 	mainFrame.HandleMouseWheel(lastMouseX, lastMouseY, e.PreciseX, e.PreciseY, e.Direction == sdl.MOUSEWHEEL_FLIPPED)
 }
 
@@ -121,12 +233,22 @@ func handleMouseMove(x, y int, buttons uint32) {
 	lastMouseX, lastMouseY = x, y
 	if mouseDownFrame != nil {
 		mouseDownFrame.MouseMove(x-mouseDownX, y-mouseDownY, buttons)
+	} else if modal := Modal(); modal != nil {
+		mx, my := modal.Pos()
+		modal.HandleMouseMove(x-mx, y-my, buttons)
 	} else {
 		mainFrame.HandleMouseMove(x, y, buttons)
 	}
 }
 
 func handleMouseDown(x, y, button int) {
+	if modal := Modal(); modal != nil {
+		mx, my := modal.Pos()
+		mouseDownFrame, mouseDownX, mouseDownY = modal.HandleMouseDown(x-mx, y-my, button)
+		mouseDownX += mx
+		mouseDownY += my
+		return
+	}
 	mouseDownFrame, mouseDownX, mouseDownY =
 		mainFrame.HandleMouseDown(x, y, button)
 }
@@ -165,7 +287,9 @@ func handleKeyDown(e *sdl.KeyboardEvent) {
 	case sdl.K_BACKSPACE:
 		handleCharInput(text.KeyBackspace)
 	case sdl.K_TAB:
-		handleCharInput(text.KeyTab)
+		if !IsCtrlCmdPressed(e.Keysym.Mod) { // Ctrl+Tab switches tabs
+			handleCharInput(text.KeyTab)
+		}
 	case sdl.K_DELETE:
 		handleCharInput(text.KeyDelete)
 	case sdl.K_RETURN, sdl.K_KP_ENTER:
@@ -192,6 +316,10 @@ func handleWindowEvent(event *sdl.WindowEvent) {
 	case sdl.WINDOWEVENT_RESIZED:
 		w, h := int(event.Data1), int(event.Data2)
 		ResizeMainFrame(w, h)
+	case sdl.WINDOWEVENT_FOCUS_GAINED:
+		for _, f := range focusHandlers {
+			f()
+		}
 	}
 }
 
@@ -216,7 +344,11 @@ func handleEvents(running *bool) {
 		case *sdl.WindowEvent:
 			handleWindowEvent(e)
 		case *sdl.QuitEvent:
-			*running = false
+			if quitHandler == nil {
+				*running = false
+			} else if Modal() == nil {
+				quitHandler()
+			}
 		}
 		event = sdl.PollEvent()
 	}
@@ -226,6 +358,11 @@ func render() {
 	Renderer.SetDrawColor(0, 0, 0, 255)
 	Renderer.Clear()
 	mainFrame.Render(0, 0)
+	for _, m := range modals {
+		x, y := m.frame.Pos()
+		Renderer.SetClipRect(nil)
+		m.frame.Render(x, y)
+	}
 	Renderer.Present()
 }
 
@@ -234,8 +371,11 @@ func Run() error {
 	ResizeMainFrame(int(w), int(h))
 
 	running := true
-	for running {
+	for running && !quitting {
 		handleEvents(&running)
+		for _, tick := range tickers {
+			tick()
+		}
 		render()
 	}
 	return nil
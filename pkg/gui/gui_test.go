@@ -0,0 +1,97 @@
+package gui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestModalFramesGiveFocusBack(t *testing.T) {
+	editor := &FrameImpl{}
+	InitFrame(editor, 0, 0, 100, 100)
+	SetFocus(editor)
+
+	first := NewDialog("First", "OK")
+	ShowModal(first)
+	second := NewDialog("Second", "OK")
+	ShowModal(second)
+	if Modal() != second || focusFrame != second {
+		t.Fatal("the second dialog is not shown over the first one")
+	}
+
+	second.Choose(0)
+	if Modal() != first || focusFrame != first {
+		t.Fatal("the first dialog does not get the focus back")
+	}
+	first.Choose(0)
+	if Modal() != nil || focusFrame != editor {
+		t.Fatal("the editor does not get the focus back")
+	}
+}
+
+func TestCloseModalUnderAnother(t *testing.T) {
+	editor := &FrameImpl{}
+	InitFrame(editor, 0, 0, 100, 100)
+	SetFocus(editor)
+
+	first := NewDialog("First", "OK")
+	ShowModal(first)
+	second := NewDialog("Second", "OK")
+	ShowModal(second)
+	first.Choose(0)
+	if Modal() != second || focusFrame != second {
+		t.Fatal("closing the first dialog hides the second one")
+	}
+	second.Choose(0)
+	if Modal() != nil || focusFrame != editor {
+		t.Fatal("the editor does not get the focus back")
+	}
+}
+
+func TestDialogShownByOnChoose(t *testing.T) {
+	editor := &FrameImpl{}
+	InitFrame(editor, 0, 0, 100, 100)
+	SetFocus(editor)
+
+	next := NewDialog("Next", "OK")
+	first := NewDialog("First", "OK", "Cancel")
+	first.OnChoose = func(choice int) { ShowModal(next) }
+	ShowModal(first)
+	first.Choose(-1)
+	if Modal() != next || focusFrame != next {
+		t.Fatal("the dialog shown by OnChoose does not get the focus")
+	}
+	next.Choose(0)
+	if Modal() != nil || focusFrame != editor {
+		t.Fatal("the editor does not get the focus back")
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  []string
+	}{
+		{"", 10, []string{""}},
+		{"Could not save", 20, []string{"Could not save"}},
+		{"Could not save a.go", 10, []string{"Could not", "save a.go"}},
+		{"open /a/very/long/path: denied", 10, []string{"open", "/a/very/lo", "ng/path:", "denied"}},
+		{"First\nsecond line", 6, []string{"First", "second", "line"}},
+	}
+	for _, tt := range tests {
+		if got := wrapText(tt.s, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrapText(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestDialogWidthIsLimited(t *testing.T) {
+	d := NewDialog(strings.Repeat("error ", 1000), "OK")
+	if w, _ := d.Size(); w > dialogMaxW {
+		t.Errorf("dialog is %d wide, want at most %d", w, dialogMaxW)
+	}
+	if len(d.lines) < 2 {
+		t.Error("the message is not wrapped")
+	}
+}
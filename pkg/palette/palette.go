@@ -0,0 +1,151 @@
+package palette
+
+import (
+	"github.com/patrikaleksandryan/coloride/pkg/color"
+)
+
+// Entry describes a color that can be applied to a run of characters.
+type Entry struct {
+	Name    string      // Used in color codes as "{name}"
+	Letter  rune        // Used in color codes, 0 if the color is written by name
+	Mark    color.Color // Vivid color that represents the entry in the interface, i.e. on toolbar buttons
+	Color   color.Color // Text color, applied if OverrideColor = true
+	BgColor color.Color // Background color, applied if OverrideBgColor = true
+	Meaning string      // What the color means in the project, i.e. "security-sensitive"
+
+	OverrideColor   bool
+	OverrideBgColor bool
+}
+
+// Palette is a list of entries indexed by color number. Color #0 is the standard one and does not change any colors.
+type Palette []Entry
+
+// entries of the current palette.
+var entries Palette
+
+func init() {
+	Reset()
+}
+
+// soft returns an entry that only tints the background.
+func soft(name string, letter rune, mark, bgColor color.Color) Entry {
+	return Entry{Name: name, Letter: letter, Mark: mark, BgColor: bgColor, OverrideBgColor: true}
+}
+
+// bright returns an entry that overrides both the text and the background colors.
+func bright(name string, letter rune, clr, bgColor color.Color) Entry {
+	return Entry{Name: name, Letter: letter, Mark: bgColor, Color: clr, BgColor: bgColor,
+		OverrideColor: true, OverrideBgColor: true}
+}
+
+// named returns an entry for a color that is only known by its name.
+func named(name string) Entry {
+	return soft(name, 0, color.MakeColor(150, 150, 150), color.MakeColor(45, 45, 45))
+}
+
+// Reset restores the default palette. Colors that were added later keep their numbers and names,
+// because texts may still refer to them, but lose their letters and appearance.
+func Reset() {
+	entries = Defaults()
+}
+
+// Defaults returns the entries the palette has after Reset.
+func Defaults() Palette {
+	defaults := defaultEntries()
+	for i := len(defaults); i < len(entries); i++ {
+		defaults = append(defaults, named(entries[i].Name))
+	}
+	return defaults
+}
+
+func defaultEntries() Palette {
+	return Palette{
+		/* Color #0 */ {Name: "none", Mark: color.White},
+		/* Color #1 */ soft("red", 'r', color.MakeColor(200, 20, 20), color.MakeColor(50, 25, 25)),
+		/* Color #2 */ soft("green", 'g', color.MakeColor(0, 170, 0), color.MakeColor(25, 50, 25)),
+		/* Color #3 */ soft("blue", 'b', color.MakeColor(0, 50, 230), color.MakeColor(25, 25, 50)),
+		/* Color #4 */ soft("yellow", 'y', color.MakeColor(200, 200, 0), color.MakeColor(50, 50, 25)),
+		/* Color #5 */ bright("bright-red", 'R', color.White, color.MakeColor(200, 0, 0)),
+		/* Color #6 */ bright("bright-green", 'G', color.White, color.MakeColor(0, 170, 0)),
+		/* Color #7 */ bright("bright-blue", 'B', color.White, color.MakeColor(0, 20, 200)),
+		/* Color #8 */ bright("bright-yellow", 'Y', color.Black, color.MakeColor(240, 230, 0)),
+		/* Color #9 */ soft("orange", 'o', color.MakeColor(230, 120, 0), color.MakeColor(55, 35, 15)),
+		/* Color #10 */ soft("purple", 'p', color.MakeColor(160, 60, 200), color.MakeColor(45, 25, 55)),
+		/* Color #11 */ soft("cyan", 'c', color.MakeColor(0, 190, 190), color.MakeColor(20, 50, 50)),
+		/* Color #12 */ soft("gray", 'k', color.MakeColor(150, 150, 150), color.MakeColor(45, 45, 45)),
+		/* Color #13 */ bright("bright-orange", 'O', color.Black, color.MakeColor(255, 140, 0)),
+		/* Color #14 */ bright("bright-purple", 'P', color.White, color.MakeColor(140, 40, 180)),
+		/* Color #15 */ bright("bright-cyan", 'C', color.Black, color.MakeColor(0, 210, 210)),
+		/* Color #16 */ bright("bright-gray", 'K', color.White, color.MakeColor(110, 110, 110)),
+	}
+}
+
+// Count returns the number of colors in the palette, including the standard color #0.
+func Count() int {
+	return len(entries)
+}
+
+// Get returns the entry of the given color number, or the entry of color #0 if there is no such color.
+func Get(clr int) *Entry {
+	if clr < 0 || clr >= len(entries) {
+		clr = 0
+	}
+	return &entries[clr]
+}
+
+// Current returns a copy of the current palette.
+func Current() Palette {
+	return append(Palette(nil), entries...)
+}
+
+// Set replaces the current palette. Texts refer to colors by number, so p must keep the colors of the current
+// palette at their numbers.
+func Set(p Palette) {
+	entries = p
+}
+
+// ByLetter returns the number of the color with the given letter, or 0 if there is no such color.
+func ByLetter(letter rune) int {
+	return entries.ByLetter(letter)
+}
+
+// ByName returns the number of the color with the given name, or 0 if there is no such color.
+func ByName(name string) int {
+	return entries.ByName(name)
+}
+
+// Resolve returns the number of the color with the given name. If the palette does not have it yet,
+// a gray color is added, so that the name is kept when the file is saved.
+func Resolve(name string) int {
+	return entries.Resolve(name)
+}
+
+// ByLetter returns the number of the color of p with the given letter, or 0 if there is no such color.
+func (p Palette) ByLetter(letter rune) int {
+	for i := 1; i < len(p); i++ {
+		if p[i].Letter == letter {
+			return i
+		}
+	}
+	return 0
+}
+
+// ByName returns the number of the color of p with the given name, or 0 if there is no such color.
+func (p Palette) ByName(name string) int {
+	for i := 1; i < len(p); i++ {
+		if p[i].Name == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// Resolve returns the number of the color of p with the given name, adding a gray color if there is none.
+func (p *Palette) Resolve(name string) int {
+	clr := p.ByName(name)
+	if clr == 0 {
+		*p = append(*p, named(name))
+		clr = len(*p) - 1
+	}
+	return clr
+}
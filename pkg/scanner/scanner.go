@@ -4,13 +4,16 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"unicode/utf8"
+
+	"github.com/patrikaleksandryan/coloride/pkg/charset"
 )
 
 const (
 	// Symbols
 
 	String      = iota
-	ColorMarker // Three slashes ("///")
+	ColorMarker // Start of a color comment, i.e. "///"
 	NewLine     // "\r", "\n" or "\r\n"
 	EOT         // End of text
 )
@@ -29,15 +32,18 @@ type Scanner struct {
 	Error               error
 	eof                 bool
 	colorMarkerDetected bool
+	marker              []rune // Start of a color comment
 
 	Sym         int
 	String      []rune // Actual data of the last scanned symbol if sym = String
 	NewLineType int    // One of New Line Type constants if sym = NewLine
 }
 
-func NewScanner(file *bufio.Reader) *Scanner {
+// NewScanner returns a scanner of file, that detects color comments starting with marker, i.e. "///".
+func NewScanner(file *bufio.Reader, marker string) *Scanner {
 	s := &Scanner{
-		file: file,
+		file:   file,
+		marker: []rune(marker),
 	}
 	s.read()
 	return s
@@ -66,27 +72,42 @@ func (s *Scanner) Scan() {
 		s.Sym = NewLine
 	} else { // String or ColorMarker
 		s.String = make([]rune, 0, 20)
-		slashes := 0
-		for s.Ch != 0 && s.Ch != '\r' && s.Ch != '\n' && slashes != 3 {
+		for s.Ch != 0 && s.Ch != '\r' && s.Ch != '\n' && !s.colorMarkerDetected {
 			s.String = append(s.String, s.Ch)
-			if s.Ch == '/' {
-				slashes++
-			} else {
-				slashes = 0
-			}
+			s.colorMarkerDetected = s.endsWithMarker()
 			s.read()
 		}
-		if slashes == 3 {
-			s.colorMarkerDetected = true
-			s.String = s.String[:len(s.String)-3]
+		if s.colorMarkerDetected {
+			s.String = s.String[:len(s.String)-len(s.marker)]
 		}
 		s.Sym = String
 	}
 }
 
+// endsWithMarker reports whether s.String ends with the color comment marker.
+func (s *Scanner) endsWithMarker() bool {
+	n := len(s.String) - len(s.marker)
+	if len(s.marker) == 0 || n < 0 {
+		return false
+	}
+	for i, r := range s.marker {
+		if s.String[n+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *Scanner) read() {
+	var size int
 	var err error
-	s.Ch, _, err = s.file.ReadRune()
+	s.Ch, size, err = s.file.ReadRune()
+	if s.Ch == utf8.RuneError && size == 1 {
+		// Invalid UTF-8, the byte is kept to be written back unchanged
+		_ = s.file.UnreadRune()
+		b, _ := s.file.ReadByte()
+		s.Ch = charset.RawByte(b)
+	}
 	if err != nil {
 		if err == io.EOF {
 			s.Ch = 0
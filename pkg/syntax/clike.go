@@ -0,0 +1,108 @@
+package syntax
+
+// States of cLikeLexer
+const (
+	cStateComment   State = 1 + iota // Inside a block comment
+	cStateRawString                  // Inside a raw string that continues on the next line
+)
+
+// cLikeLexer highlights languages with C-style comments, i.e. C and JavaScript.
+type cLikeLexer struct {
+	keywords     map[string]bool
+	quotes       string // Quotes of single-line strings with escapes
+	rawQuote     rune   // Quote of multi-line strings, 0 if there are none
+	rawEscapes   bool   // Whether a backslash escapes characters in multi-line strings
+	preprocessor bool   // Whether lines starting with '#' are preprocessor directives
+}
+
+func (l *cLikeLexer) Scan(line []rune, pos int, state State) (class, length int, newState State) {
+	s := line[pos:]
+	c := s[0]
+	switch {
+	case state == cStateComment:
+		length, found := scanUntil(s, "*/")
+		if found {
+			return CComment, length, 0
+		}
+		return CComment, length, state
+	case state == cStateRawString:
+		length, closed := scanRest(s, string(l.rawQuote), l.rawEscapes)
+		if closed {
+			return CString, length, 0
+		}
+		return CString, length, state
+	case isWhitespace(c):
+		return CNone, 1, 0
+	case isAlpha(c) || c == '$':
+		length = scanWord(s)
+		return classifyWord(s, length, l.keywords), length, 0
+	case isNumeric(c) || c == '.' && len(s) > 1 && isNumeric(s[1]):
+		return CNumber, scanNumber(s), 0
+	case hasPrefix(s, "//"):
+		return CComment, len(s), 0
+	case hasPrefix(s, "/*"):
+		length, found := scanUntil(s[2:], "*/")
+		if found {
+			return CComment, length + 2, 0
+		}
+		return CComment, length + 2, cStateComment
+	case c == '#' && l.preprocessor && isLineStart(line, pos):
+		length = 1
+		for length != len(s) && isWhitespace(s[length]) {
+			length++
+		}
+		for length != len(s) && isAlpha(s[length]) {
+			length++
+		}
+		return CKeyword, length, 0
+	case l.rawQuote != 0 && c == l.rawQuote:
+		length, closed := scanQuoted(s, string(c), l.rawEscapes)
+		if closed {
+			return CString, length, 0
+		}
+		return CString, length, cStateRawString
+	case containsRune(l.quotes, c):
+		length, _ := scanQuoted(s, string(c), true)
+		return CString, length, 0
+	}
+	return CNone, 1, 0
+}
+
+func containsRune(s string, c rune) bool {
+	for _, r := range s {
+		if r == c {
+			return true
+		}
+	}
+	return false
+}
+
+func keywordSet(words ...string) map[string]bool {
+	m := make(map[string]bool, len(words))
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}
+
+var cLexer = &cLikeLexer{
+	keywords: keywordSet("auto", "break", "case", "char", "const", "continue", "default", "do", "double",
+		"else", "enum", "extern", "float", "for", "goto", "if", "inline", "int", "long", "register",
+		"restrict", "return", "short", "signed", "sizeof", "static", "struct", "switch", "typedef", "union",
+		"unsigned", "void", "volatile", "while", "_Bool", "_Complex", "_Imaginary", "bool", "true", "false",
+		"NULL", "class", "namespace", "template", "typename", "public", "private", "protected", "virtual",
+		"new", "delete", "this", "nullptr", "using", "operator", "try", "catch", "throw"),
+	quotes:       "\"'",
+	preprocessor: true,
+}
+
+var jsLexer = &cLikeLexer{
+	keywords: keywordSet("await", "break", "case", "catch", "class", "const", "continue", "debugger",
+		"default", "delete", "do", "else", "export", "extends", "false", "finally", "for", "function", "if",
+		"import", "in", "instanceof", "let", "new", "null", "of", "return", "static", "super", "switch",
+		"this", "throw", "true", "try", "typeof", "undefined", "var", "void", "while", "with", "yield",
+		"async", "from"),
+	quotes:     "\"'",
+	rawQuote:   '`',
+	rawEscapes: true,
+}
@@ -0,0 +1,207 @@
+package syntax
+
+import "unicode"
+
+// States of goLexer
+const (
+	goStateComment   State = 1 + iota // Inside a block comment
+	goStateRawString                  // Inside a raw string
+	goStateString                     // Inside an interpreted string, after an escape sequence
+	goStateRune                       // Inside a rune literal, after an escape sequence
+)
+
+// goLexer highlights Go source code as defined in the language specification.
+// Escape sequences are separate symbols, so interpreted strings and runes are split into several symbols,
+// which always end on the same line.
+type goLexer struct{}
+
+var goKeywords = keywordSet("break", "default", "func", "interface", "select", "case", "defer", "go", "map",
+	"struct", "chan", "else", "goto", "package", "switch", "const", "fallthrough", "if", "range", "type",
+	"continue", "for", "import", "return", "var")
+
+var goTypes = keywordSet("any", "bool", "byte", "comparable", "complex64", "complex128", "error", "float32",
+	"float64", "int", "int8", "int16", "int32", "int64", "rune", "string", "uint", "uint8", "uint16", "uint32",
+	"uint64", "uintptr")
+
+var goBuiltins = keywordSet("true", "false", "iota", "nil", "append", "cap", "clear", "close", "complex",
+	"copy", "delete", "imag", "len", "make", "max", "min", "new", "panic", "print", "println", "real", "recover")
+
+// goOperators are the operators and punctuation, longer ones first.
+var goOperators = []string{"<<=", ">>=", "&^=", "...", "&&", "||", "<-", "++", "--", "==", "!=", "<=", ">=",
+	":=", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<<", ">>", "&^", "~"}
+
+func (goLexer) Scan(line []rune, pos int, state State) (class, length int, newState State) {
+	s := line[pos:]
+	c := s[0]
+	switch {
+	case state == goStateComment:
+		length, found := scanUntil(s, "*/")
+		if found {
+			return CComment, length, 0
+		}
+		return CComment, length, state
+	case state == goStateRawString:
+		length, closed := scanRest(s, "`", false)
+		if closed {
+			return CString, length, 0
+		}
+		return CString, length, state
+	case state == goStateString:
+		return scanGoString(s, 0, '"')
+	case state == goStateRune:
+		return scanGoString(s, 0, '\'')
+	case isWhitespace(c):
+		return CNone, 1, 0
+	case isGoLetter(c):
+		length = scanGoIdent(s)
+		word := string(s[:length])
+		switch {
+		case goKeywords[word]:
+			return CKeyword, length, 0
+		case goTypes[word]:
+			return CType, length, 0
+		case goBuiltins[word]:
+			return CBuiltin, length, 0
+		case startsWithLParen(s[length:]):
+			return CProcCall, length, 0
+		}
+		return CIdent, length, 0
+	case isNumeric(c) || c == '.' && len(s) > 1 && isNumeric(s[1]):
+		return CNumber, scanGoNumber(s), 0
+	case hasPrefix(s, "//"):
+		return CComment, len(s), 0
+	case hasPrefix(s, "/*"):
+		length, found := scanUntil(s[2:], "*/")
+		if found {
+			return CComment, length + 2, 0
+		}
+		return CComment, length + 2, goStateComment
+	case c == '`':
+		length, closed := scanQuoted(s, "`", false)
+		if closed {
+			return CString, length, 0
+		}
+		return CString, length, goStateRawString
+	case c == '"' || c == '\'':
+		return scanGoString(s, 1, c)
+	}
+	for _, op := range goOperators {
+		if hasPrefix(s, op) {
+			return COperator, len(op), 0
+		}
+	}
+	return COperator, 1, 0
+}
+
+// isGoLetter reports whether c can start an identifier. Identifiers may contain any Unicode letters and digits.
+func isGoLetter(c rune) bool {
+	return isAlpha(c) || c >= 0x80 && unicode.IsLetter(c)
+}
+
+// scanGoIdent returns the length of the identifier at the beginning of s.
+func scanGoIdent(s []rune) int {
+	length := 1
+	for length != len(s) && (isGoLetter(s[length]) || isNumeric(s[length]) || s[length] >= 0x80 && unicode.IsDigit(s[length])) {
+		length++
+	}
+	return length
+}
+
+// scanGoString scans the part of an interpreted string or a rune literal from s[start:] up to the
+// closing quote or the next escape sequence. An escape sequence at s[0] is returned as a separate symbol.
+// The string ends at the end of the line, even if it is not closed.
+func scanGoString(s []rune, start int, quote rune) (class, length int, newState State) {
+	state := goStateString
+	if quote == '\'' {
+		state = goStateRune
+	}
+	if start == 0 && s[0] == '\\' {
+		length = min(escapeLength(s), len(s))
+		if length == len(s) {
+			return CEscape, length, 0
+		}
+		return CEscape, length, state
+	}
+	length = start
+	for length != len(s) {
+		if s[length] == quote {
+			return CString, length + 1, 0
+		} else if s[length] == '\\' {
+			return CString, length, state
+		}
+		length++
+	}
+	return CString, length, 0
+}
+
+// escapeLength returns the length of the escape sequence at the beginning of s, i.e. 2 for "\n" or 6 for "\u00e9".
+func escapeLength(s []rune) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case 'x':
+		return 4
+	case 'u':
+		return 6
+	case 'U':
+		return 10
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		return 4
+	}
+	return 2
+}
+
+// scanGoNumber returns the length of the integer, floating-point or imaginary literal at the beginning of s.
+func scanGoNumber(s []rune) int {
+	digits := isNumeric
+	exponent := "eE"
+	length := 0
+	if len(s) > 1 && s[0] == '0' {
+		switch s[1] {
+		case 'x', 'X':
+			digits, exponent, length = isHexDigit, "pP", 2
+		case 'b', 'B':
+			digits, exponent, length = isBinaryDigit, "", 2
+		case 'o', 'O':
+			digits, exponent, length = isOctalDigit, "", 2
+		}
+	}
+	length = scanDigits(s, length, digits)
+	if exponent != "" {
+		if length != len(s) && s[length] == '.' {
+			length = scanDigits(s, length+1, digits)
+		}
+		if length != len(s) && containsRune(exponent, s[length]) {
+			length++
+			if length != len(s) && (s[length] == '+' || s[length] == '-') {
+				length++
+			}
+			length = scanDigits(s, length, isNumeric)
+		}
+	}
+	if length != len(s) && s[length] == 'i' {
+		length++
+	}
+	return length
+}
+
+// scanDigits skips the digits and underscores in s starting with s[pos] and returns the position after them.
+func scanDigits(s []rune, pos int, digit func(c rune) bool) int {
+	for pos != len(s) && (digit(s[pos]) || s[pos] == '_') {
+		pos++
+	}
+	return pos
+}
+
+func isHexDigit(c rune) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func isBinaryDigit(c rune) bool {
+	return c == '0' || c == '1'
+}
+
+func isOctalDigit(c rune) bool {
+	return '0' <= c && c <= '7'
+}
@@ -0,0 +1,219 @@
+package syntax
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// symbol is a symbol found by a lexer.
+type symbol struct {
+	lineNum, pos int // 1-based line number, 0-based position in the line in characters
+	class        int
+	text         string
+}
+
+// scanText splits src into lines and scans them with lexer. Whitespace is skipped.
+func scanText(lexer Lexer, src []byte) []symbol {
+	var symbols []symbol
+	var state State
+	for i, l := range splitLines(src) {
+		line := []rune(l)
+		for pos := 0; pos < len(line); {
+			class, length, newState := lexer.Scan(line, pos, state)
+			text := string(line[pos : pos+length])
+			if class != CNone || strings.TrimSpace(text) != "" {
+				symbols = append(symbols, symbol{lineNum: i + 1, pos: pos, class: class, text: text})
+			}
+			pos += length
+			state = newState
+		}
+	}
+	return symbols
+}
+
+// splitLines splits src into lines the way texts are loaded.
+func splitLines(src []byte) []string {
+	s := strings.ReplaceAll(string(src), "\r\n", "\n")
+	return strings.Split(strings.ReplaceAll(s, "\r", "\n"), "\n")
+}
+
+// checkGoFile compares the symbols of goLexer in a Go source file with the tokens of go/scanner.
+// Every token must start a symbol of the right class; identifiers, numbers and operators must have
+// the same length. Strings and comments may be split into several symbols.
+func checkGoFile(src []byte) error {
+	symbols := make(map[[2]int]symbol)
+	for _, sym := range scanText(goLexer{}, src) {
+		symbols[[2]int{sym.lineNum, sym.pos}] = sym
+	}
+	lines := bytes.Split(src, []byte("\n"))
+
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	var scanErr error
+	s.Init(file, src, func(pos token.Position, msg string) { scanErr = fmt.Errorf("%s: %s", pos, msg) },
+		scanner.ScanComments)
+	for {
+		p, tok, lit := s.Scan()
+		if tok == token.EOF || scanErr != nil {
+			return nil // Files that are not valid Go are not compared
+		}
+		if tok == token.SEMICOLON && lit != ";" {
+			continue // Inserted automatically
+		}
+		pos := fset.Position(p)
+		line := lines[pos.Line-1]
+		col := utf8.RuneCount(line[:pos.Column-1])
+		sym, ok := symbols[[2]int{pos.Line, col}]
+		if !ok {
+			return fmt.Errorf("%d:%d: no symbol at %s %q", pos.Line, col, tok, lit)
+		}
+		if lit == "" {
+			lit = tok.String()
+		}
+		var classes []int
+		sameLength := true
+		switch {
+		case tok == token.IDENT:
+			classes = []int{CIdent, CProcCall, CType, CBuiltin}
+		case tok.IsKeyword():
+			classes = []int{CKeyword}
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			classes = []int{CNumber}
+		case tok == token.CHAR || tok == token.STRING:
+			classes, sameLength = []int{CString}, false
+		case tok == token.COMMENT:
+			classes, sameLength = []int{CComment}, false
+		default:
+			classes = []int{COperator}
+		}
+		if !containsInt(classes, sym.class) || sameLength && sym.text != lit {
+			return fmt.Errorf("%d:%d: %s %q scanned as class %d %q", pos.Line, col, tok, lit, sym.class, sym.text)
+		}
+	}
+}
+
+func containsInt(a []int, x int) bool {
+	for _, y := range a {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var classNames = []string{
+	CNone: "none", CComment: "comment", CIdent: "ident", CKeyword: "keyword", CString: "string",
+	CNumber: "number", CProcCall: "proc-call", CBuiltin: "builtin", CType: "type", COperator: "operator",
+	CEscape: "escape",
+}
+
+// formatSymbols returns the symbols in the format of the golden files: one symbol per line.
+func formatSymbols(symbols []symbol) []byte {
+	var b bytes.Buffer
+	for _, sym := range symbols {
+		fmt.Fprintf(&b, "%d:%d %s %q\n", sym.lineNum, sym.pos, classNames[sym.class], sym.text)
+	}
+	return b.Bytes()
+}
+
+// TestGoLexerGolden compares the symbols of the Go files in testdata/go with the .golden files next to them.
+// Some of the files are copied from the standard library. Run with -update after changing the lexer.
+func TestGoLexerGolden(t *testing.T) {
+	fnames, err := filepath.Glob(filepath.Join("testdata", "go", "*.go"))
+	if err != nil || len(fnames) == 0 {
+		t.Fatal("no test files", err)
+	}
+	for _, fname := range fnames {
+		src, err := os.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := formatSymbols(scanText(goLexer{}, src))
+		golden := strings.TrimSuffix(fname, ".go") + ".golden"
+		if *update {
+			err = os.WriteFile(golden, got, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+		for i := 0; i < len(gotLines) && i < len(wantLines); i++ {
+			if gotLines[i] != wantLines[i] {
+				t.Errorf("%s: symbol %d is %s, want %s", fname, i+1, gotLines[i], wantLines[i])
+				break
+			}
+		}
+		if len(gotLines) != len(wantLines) {
+			t.Errorf("%s: %d symbols, want %d", fname, len(gotLines)-1, len(wantLines)-1)
+		}
+		if err := checkGoFile(src); err != nil {
+			t.Errorf("%s:%v", fname, err)
+		}
+	}
+}
+
+var stdlib = flag.Bool("stdlib", false, "compare the Go lexer with go/scanner on the whole standard library")
+
+// TestGoLexerStdlib scans the sources of the Go standard library, by default only some packages.
+func TestGoLexerStdlib(t *testing.T) {
+	root := filepath.Join(runtime.GOROOT(), "src")
+	if _, err := os.Stat(root); err != nil {
+		t.Skip("Go sources not found:", err)
+	}
+	dirs := []string{"strconv", "unicode", "math", "fmt", "go", "regexp", "text/template", "encoding/json"}
+	if *stdlib {
+		dirs = []string{"."}
+	}
+	files, failed := 0, 0
+	for _, dir := range dirs {
+		err := filepath.WalkDir(filepath.Join(root, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && d.Name() == "testdata" {
+				return filepath.SkipDir
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".go") {
+				return nil
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files++
+			if err := checkGoFile(src); err != nil {
+				failed++
+				if failed <= 20 {
+					t.Errorf("%s:%v", path, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if files == 0 {
+		t.Error("no files scanned")
+	} else if failed != 0 {
+		t.Errorf("%d of %d files differ", failed, files)
+	}
+}
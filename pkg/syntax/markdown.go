@@ -0,0 +1,49 @@
+package syntax
+
+// States of markdownLexer
+const (
+	mdStateFence State = 1 // Inside a fenced code block
+)
+
+// markdownLexer highlights headings and list markers as keywords, code as strings, quotes as comments
+// and link targets as procedure calls.
+type markdownLexer struct{}
+
+func (markdownLexer) Scan(line []rune, pos int, state State) (class, length int, newState State) {
+	s := line[pos:]
+	c := s[0]
+	atStart := isLineStart(line, pos)
+	switch {
+	case state == mdStateFence:
+		if atStart && hasPrefix(trimLeft(s), "```") {
+			return CString, len(s), 0
+		}
+		return CString, len(s), state
+	case isWhitespace(c):
+		return CNone, 1, 0
+	case atStart && hasPrefix(s, "```"):
+		return CString, len(s), mdStateFence
+	case atStart && c == '#':
+		return CKeyword, len(s), 0
+	case atStart && c == '>':
+		return CComment, len(s), 0
+	case atStart && (c == '-' || c == '*' || c == '+') && len(s) > 1 && s[1] == ' ':
+		return CKeyword, 1, 0
+	case c == '`':
+		length, _ := scanUntil(s[1:], "`")
+		return CString, length + 1, 0
+	case c == '(' && pos != 0 && line[pos-1] == ']':
+		length, _ := scanUntil(s[1:], ")")
+		return CProcCall, length + 1, 0
+	case isAlphaNumeric(c):
+		return CNone, scanWord(s), 0
+	}
+	return CNone, 1, 0
+}
+
+func trimLeft(s []rune) []rune {
+	for len(s) != 0 && isWhitespace(s[0]) {
+		s = s[1:]
+	}
+	return s
+}
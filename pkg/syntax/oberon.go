@@ -0,0 +1,51 @@
+package syntax
+
+// oberonLexer highlights Oberon and Component Pascal. Comments "(* ... *)" can be nested,
+// the state is the nesting level of comments.
+type oberonLexer struct{}
+
+var oberonKeywords = keywordSet("ARRAY", "BEGIN", "BY", "CASE", "CONST", "DIV", "DO", "ELSE", "ELSIF",
+	"END", "EXIT", "FALSE", "FOR", "IF", "IMPORT", "IN", "IS", "LOOP", "MOD", "MODULE", "NIL", "OF", "OR",
+	"POINTER", "PROCEDURE", "RECORD", "REPEAT", "RETURN", "THEN", "TO", "TRUE", "TYPE", "UNTIL", "VAR",
+	"WHILE", "WITH", "ABSTRACT", "EXTENSIBLE", "LIMITED", "EMPTY", "CLOSE", "OUT")
+
+func (oberonLexer) Scan(line []rune, pos int, state State) (class, length int, newState State) {
+	s := line[pos:]
+	c := s[0]
+	switch {
+	case state != 0:
+		return scanOberonComment(s, 0, state)
+	case hasPrefix(s, "(*"):
+		return scanOberonComment(s, 2, 1)
+	case isWhitespace(c):
+		return CNone, 1, 0
+	case isAlpha(c):
+		length = scanWord(s)
+		return classifyWord(s, length, oberonKeywords), length, 0
+	case isNumeric(c):
+		// Hexadecimal numbers and characters end with H or X, i.e. 0FFH and 0DX
+		return CNumber, scanNumber(s), 0
+	case c == '"' || c == '\'':
+		length, _ := scanQuoted(s, string(c), false)
+		return CString, length, 0
+	}
+	return CNone, 1, 0
+}
+
+// scanOberonComment scans the comment from s[start:] on, with the given nesting level.
+// The whole comment is returned, if it ends on this line.
+func scanOberonComment(s []rune, start int, level State) (class, length int, newState State) {
+	length = start
+	for length != len(s) && level != 0 {
+		if hasPrefix(s[length:], "(*") {
+			level++
+			length += 2
+		} else if hasPrefix(s[length:], "*)") {
+			level--
+			length += 2
+		} else {
+			length++
+		}
+	}
+	return CComment, length, level
+}
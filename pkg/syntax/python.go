@@ -0,0 +1,60 @@
+package syntax
+
+// States of pythonLexer
+const (
+	pyStateSingle State = 1 + iota // Inside a ''' string
+	pyStateDouble                  // Inside a """ string
+)
+
+var pythonKeywords = keywordSet("False", "None", "True", "and", "as", "assert", "async", "await", "break",
+	"class", "continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global", "if",
+	"import", "in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try", "while",
+	"with", "yield", "match", "case", "self")
+
+type pythonLexer struct{}
+
+func (pythonLexer) Scan(line []rune, pos int, state State) (class, length int, newState State) {
+	s := line[pos:]
+	c := s[0]
+	switch {
+	case state == pyStateSingle || state == pyStateDouble:
+		quote := `'''`
+		if state == pyStateDouble {
+			quote = `"""`
+		}
+		length, closed := scanRest(s, quote, true)
+		if closed {
+			return CString, length, 0
+		}
+		return CString, length, state
+	case isWhitespace(c):
+		return CNone, 1, 0
+	case isAlpha(c):
+		length = scanWord(s)
+		return classifyWord(s, length, pythonKeywords), length, 0
+	case isNumeric(c) || c == '.' && len(s) > 1 && isNumeric(s[1]):
+		return CNumber, scanNumber(s), 0
+	case c == '#':
+		return CComment, len(s), 0
+	case c == '@' && isLineStart(line, pos): // Decorator
+		length = 1
+		for length != len(s) && (isAlphaNumeric(s[length]) || s[length] == '.') {
+			length++
+		}
+		return CKeyword, length, 0
+	case hasPrefix(s, `'''`) || hasPrefix(s, `"""`):
+		quote := string(s[:3])
+		length, closed := scanQuoted(s, quote, true)
+		if closed {
+			return CString, length, 0
+		}
+		if c == '\'' {
+			return CString, length, pyStateSingle
+		}
+		return CString, length, pyStateDouble
+	case c == '\'' || c == '"':
+		length, _ := scanQuoted(s, string(c), true)
+		return CString, length, 0
+	}
+	return CNone, 1, 0
+}
@@ -0,0 +1,143 @@
+package syntax
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// ColorComment is the comment that holds the color code of a line, i.e. "///" in Go or "(*/ ... *)" in Oberon.
+type ColorComment struct {
+	Open  string // Marker that starts the comment
+	Close string // Marker that ends the comment, "" if the comment ends at the end of the line
+}
+
+// DefaultColorComment is used for texts that have no file name yet and for files of unknown types.
+var DefaultColorComment = ColorComment{Open: "///"}
+
+// registration binds a lexer and a color comment to file extensions and interpreters of shebang lines.
+type registration struct {
+	name         string
+	lexer        Lexer
+	colorComment ColorComment
+	extensions   []string // Lowercase, with the dot, i.e. ".go"
+	interpreters []string // i.e. "python" for "#!/usr/bin/env python3"
+}
+
+var registry []*registration
+
+// Register adds a language to the registry under the given name.
+func Register(name string, lexer Lexer, colorComment ColorComment, extensions, interpreters []string) {
+	registry = append(registry, &registration{
+		name:         name,
+		lexer:        lexer,
+		colorComment: colorComment,
+		extensions:   extensions,
+		interpreters: interpreters,
+	})
+}
+
+// ByName returns the lexer registered under the given name, or nil.
+func ByName(name string) Lexer {
+	for _, reg := range registry {
+		if reg.name == name {
+			return reg.lexer
+		}
+	}
+	return nil
+}
+
+// Default returns the lexer of texts that have no file name yet.
+func Default() Lexer {
+	return ByName("go")
+}
+
+// ForFile returns the lexer for the file fname, chosen by the extension, or else by the shebang in
+// firstLine. Files of unknown types get the plain lexer, which does not highlight anything.
+func ForFile(fname, firstLine string) Lexer {
+	if reg := find(fname, firstLine); reg != nil {
+		return reg.lexer
+	}
+	return ByName("plain")
+}
+
+// ColorCommentFor returns the color comment for the file fname, chosen the same way as in ForFile.
+func ColorCommentFor(fname, firstLine string) ColorComment {
+	if reg := find(fname, firstLine); reg != nil {
+		return reg.colorComment
+	}
+	return DefaultColorComment
+}
+
+// find returns the registration of the language of the file fname, or nil if the type is unknown.
+func find(fname, firstLine string) *registration {
+	ext := strings.ToLower(filepath.Ext(fname))
+	if ext != "" {
+		for _, reg := range registry {
+			for _, e := range reg.extensions {
+				if e == ext {
+					return reg
+				}
+			}
+		}
+	}
+	if interpreter := shebangInterpreter(firstLine); interpreter != "" {
+		for _, reg := range registry {
+			for _, i := range reg.interpreters {
+				if i == interpreter {
+					return reg
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// shebangInterpreter returns the name of the interpreter of a shebang line without the version,
+// i.e. "python" for "#!/usr/bin/env python3", or "" if line is not a shebang.
+func shebangInterpreter(line string) string {
+	cmd, ok := strings.CutPrefix(line, "#!")
+	if !ok {
+		return ""
+	}
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return ""
+	}
+	name := filepath.Base(fields[0])
+	if name == "env" {
+		// Skip options of env, i.e. "-S"
+		name = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				name = filepath.Base(f)
+				break
+			}
+		}
+	}
+	return strings.TrimRight(name, "0123456789.")
+}
+
+// plainLexer does not highlight anything.
+type plainLexer struct{}
+
+func (plainLexer) Scan(line []rune, pos int, state State) (class, length int, newState State) {
+	return CNone, len(line) - pos, 0
+}
+
+func init() {
+	slashes := DefaultColorComment
+	hashes := ColorComment{Open: "###"}
+	dashes := ColorComment{Open: "--- "}
+	Register("plain", plainLexer{}, slashes, []string{".txt"}, nil)
+	Register("go", goLexer{}, slashes, []string{".go"}, nil)
+	Register("c", cLexer, slashes, []string{".c", ".h", ".cc", ".cpp", ".cxx", ".hpp", ".hh"}, []string{"tcc"})
+	Register("javascript", jsLexer, slashes, []string{".js", ".mjs", ".cjs", ".jsx"},
+		[]string{"node", "nodejs", "deno"})
+	Register("python", pythonLexer{}, hashes, []string{".py", ".pyw", ".pyi"}, []string{"python"})
+	Register("oberon", oberonLexer{}, ColorComment{Open: "(*/", Close: "*)"},
+		[]string{".mod", ".ob", ".obn", ".ob07", ".ob2"}, nil)
+	Register("markdown", markdownLexer{}, slashes, []string{".md", ".markdown"}, nil)
+	Register("shell", plainLexer{}, hashes, []string{".sh", ".bash", ".zsh"}, []string{"sh", "bash", "zsh", "dash", "ksh"})
+	Register("sql", plainLexer{}, dashes, []string{".sql"}, nil)
+	Register("lua", plainLexer{}, dashes, []string{".lua"}, []string{"lua", "luajit"})
+}
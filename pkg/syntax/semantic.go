@@ -0,0 +1,184 @@
+package syntax
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Span is an identifier classified by semantic analysis.
+type Span struct {
+	Line   int // 1-based
+	Column int // 0-based, in characters
+	Length int
+	Class  int
+}
+
+// AnalyzeGo parses and type-checks a single Go file and returns the classes of its identifiers, sorted by position.
+// Imported packages are not loaded, so identifiers that belong to them are left out, as well as identifiers
+// in the parts of the code that do not compile.
+func AnalyzeGo(src []byte) (spans []Span) {
+	defer func() {
+		// go/types is not guaranteed to handle every broken syntax tree
+		if recover() != nil {
+			spans = nil
+		}
+	}()
+
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, "", parserSource(src), parser.AllErrors|parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{
+		Importer: stubImporter{},
+		Error:    func(error) {}, // Continue after errors
+	}
+	pkg, _ := conf.Check(file.Name.Name, fset, []*ast.File{file}, info)
+	if pkg == nil {
+		return nil
+	}
+
+	params := make(map[types.Object]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if fn, ok := n.(*ast.FuncDecl); ok && fn.Recv != nil {
+			addParams(params, info, fn.Recv)
+		} else if ft, ok := n.(*ast.FuncType); ok {
+			addParams(params, info, ft.Params)
+			addParams(params, info, ft.Results)
+		}
+		return true
+	})
+
+	lines := lineOffsets(src)
+	add := func(ident *ast.Ident, obj types.Object) {
+		class := classifyObject(obj, pkg, params)
+		if class == CNone {
+			return
+		}
+		offset := fset.Position(ident.Pos()).Offset
+		lineNum := sort.Search(len(lines), func(i int) bool { return lines[i] > offset })
+		spans = append(spans, Span{
+			Line:   lineNum,
+			Column: utf8.RuneCount(src[lines[lineNum-1]:offset]),
+			Length: utf8.RuneCountInString(ident.Name),
+			Class:  class,
+		})
+	}
+	for ident, obj := range info.Defs {
+		if obj != nil {
+			add(ident, obj)
+		}
+	}
+	for ident, obj := range info.Uses {
+		add(ident, obj)
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].Line != spans[j].Line {
+			return spans[i].Line < spans[j].Line
+		}
+		return spans[i].Column < spans[j].Column
+	})
+	return spans
+}
+
+// classifyObject returns the class of the object, or CNone if the lexical class should be kept.
+func classifyObject(obj types.Object, pkg *types.Package, params map[types.Object]bool) int {
+	switch obj := obj.(type) {
+	case *types.PkgName:
+		return CPackage
+	case *types.Const:
+		if obj.Pkg() != nil {
+			return CConst
+		}
+	case *types.TypeName:
+		if obj.Pkg() != nil {
+			return CTypeName
+		}
+	case *types.Func:
+		return CFunc
+	case *types.Var:
+		switch {
+		case obj.IsField():
+			return CField
+		case params[obj]:
+			return CParam
+		case obj.Parent() != nil && obj.Parent() != pkg.Scope():
+			return CLocal
+		}
+	}
+	return CNone
+}
+
+// addParams adds the objects declared in the field list to params.
+func addParams(params map[types.Object]bool, info *types.Info, fields *ast.FieldList) {
+	if fields == nil {
+		return
+	}
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			if obj := info.Defs[name]; obj != nil {
+				params[obj] = true
+			}
+		}
+	}
+}
+
+// lineOffsets returns the byte offsets of the beginnings of lines in src. Lines end with LF, CRLF or CR,
+// as in the scanner of texts.
+func lineOffsets(src []byte) []int {
+	offsets := []int{0}
+	for i, b := range src {
+		if b == '\n' || b == '\r' && (i+1 == len(src) || src[i+1] != '\n') {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+// parserSource returns src with every CR that is not followed by LF replaced with LF, because go/parser
+// ends line comments only at LF. The offsets stay the same.
+func parserSource(src []byte) []byte {
+	if !bytes.ContainsRune(src, '\r') {
+		return src
+	}
+	src = bytes.Clone(src)
+	for i, b := range src {
+		if b == '\r' && (i+1 == len(src) || src[i+1] != '\n') {
+			src[i] = '\n'
+		}
+	}
+	return src
+}
+
+// stubImporter makes empty packages instead of loading the imported ones, so that a file can be
+// analyzed on its own.
+type stubImporter struct{}
+
+func (stubImporter) Import(importPath string) (*types.Package, error) {
+	pkg := types.NewPackage(importPath, packageName(importPath))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// packageName guesses the name of a package from its import path, i.e. "yaml" for "gopkg.in/yaml.v3"
+// and "sdl" for "github.com/veandco/go-sdl2/sdl".
+func packageName(importPath string) string {
+	name := path.Base(importPath)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = path.Base(path.Dir(importPath))
+	}
+	name, _, _ = strings.Cut(name, ".")
+	name = strings.TrimPrefix(name, "go-")
+	return strings.ReplaceAll(name, "-", "_")
+}
@@ -0,0 +1,84 @@
+package syntax
+
+import (
+	"strings"
+	"testing"
+)
+
+const semanticSrc = `package p
+
+import "fmt"
+
+// point is a point, größe is its size
+type point struct{ x, größe int }
+
+func (p point) scale(k int) (r point) {
+	n := p.x * k
+	r.x = n
+	fmt.Println(n)
+	return r
+}
+`
+
+// spanAt returns the class of the span at the line and column, or -1 if there is none.
+func spanAt(spans []Span, lineNum, column int) int {
+	for _, span := range spans {
+		if span.Line == lineNum && span.Column == column {
+			return span.Class
+		}
+	}
+	return -1
+}
+
+func TestAnalyzeGo(t *testing.T) {
+	tests := []struct {
+		lineNum, column int
+		class           int
+	}{
+		{3, 8, -1},        // "fmt" in the import is a string
+		{6, 5, CTypeName}, // point
+		{6, 19, CField},   // x
+		{6, 22, CField},   // größe
+		{8, 6, CParam},    // p, the receiver
+		{8, 15, CFunc},    // scale
+		{8, 21, CParam},   // k
+		{8, 29, CParam},   // r, the result
+		{9, 1, CLocal},    // n
+		{9, 8, CField},    // x
+		{10, 1, CParam},   // r
+		{11, 1, CPackage}, // fmt
+		{11, 5, -1},       // Println is unknown without the package
+		{11, 13, CLocal},  // n
+		{12, 8, CParam},   // r
+	}
+	for _, eol := range []string{"\n", "\r\n", "\r"} {
+		spans := AnalyzeGo([]byte(strings.ReplaceAll(semanticSrc, "\n", eol)))
+		for _, tt := range tests {
+			if got := spanAt(spans, tt.lineNum, tt.column); got != tt.class {
+				t.Errorf("%q: %d:%d has class %d, want %d", eol, tt.lineNum, tt.column, got, tt.class)
+			}
+		}
+	}
+}
+
+func TestAnalyzeGoBrokenCode(t *testing.T) {
+	srcs := []string{
+		"",
+		"package",
+		"package p\nfunc f(a int) {\n\tb := a +\n",
+		"package p\nfunc f(a int) int {\n\treturn a + undefined\n}\n",
+		"package p\ntype t struct{ t }\nfunc (x *t) f() { x. }\n",
+	}
+	for _, src := range srcs {
+		AnalyzeGo([]byte(src)) // Must not panic
+	}
+
+	// The identifiers of the correct parts are classified
+	spans := AnalyzeGo([]byte(srcs[3]))
+	if got := spanAt(spans, 2, 5); got != CFunc {
+		t.Errorf("f has class %d, want %d", got, CFunc)
+	}
+	if got := spanAt(spans, 3, 8); got != CParam {
+		t.Errorf("a has class %d, want %d", got, CParam)
+	}
+}
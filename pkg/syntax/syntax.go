@@ -9,37 +9,42 @@ const (
 	CString
 	CNumber
 	CProcCall
+	CBuiltin  // Predeclared functions and constants, i.e. len and nil
+	CType     // Predeclared types, i.e. int and string
+	COperator // Operators and punctuation
+	CEscape   // Escape sequences in strings, i.e. \n
+
+	// Classes of identifiers found by semantic analysis, see AnalyzeGo
+
+	CLocal    // Local variable
+	CParam    // Parameter or result of a function
+	CField    // Field of a struct
+	CTypeName // Declared type
+	CPackage  // Imported package
+	CConst    // Declared constant
+	CFunc     // Declared function or method
 )
 
-var (
-	keywords = map[string]bool{
-		"break":       true,
-		"default":     true,
-		"func":        true,
-		"interface":   true,
-		"select":      true,
-		"case":        true,
-		"defer":       true,
-		"go":          true,
-		"map":         true,
-		"struct":      true,
-		"chan":        true,
-		"else":        true,
-		"goto":        true,
-		"package":     true,
-		"switch":      true,
-		"const":       true,
-		"fallthrough": true,
-		"if":          true,
-		"range":       true,
-		"type":        true,
-		"continue":    true,
-		"for":         true,
-		"import":      true,
-		"return":      true,
-		"var":         true,
+// State is the state of a lexer between symbols, i.e. inside a multi-line comment or string.
+// Every lexer defines its own states; 0 always means the normal state.
+type State int
+
+// Lexer splits lines of source code into symbols.
+type Lexer interface {
+	// Scan returns the class (one of c- constants, see above) and the length of the symbol that starts at
+	// line[pos], and the state after it. pos < len(line).
+	Scan(line []rune, pos int, state State) (class, length int, newState State)
+}
+
+// ScanLine scans the whole line starting in the given state and returns the state at its end.
+func ScanLine(lexer Lexer, line []rune, state State) State {
+	for pos := 0; pos < len(line); {
+		_, length, newState := lexer.Scan(line, pos, state)
+		pos += length
+		state = newState
 	}
-)
+	return state
+}
 
 func isWhitespace(c rune) bool {
 	return c <= ' '
@@ -57,13 +62,16 @@ func isAlphaNumeric(c rune) bool {
 	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
 }
 
-// isEndOfMLComment report whether s starts with the end of a multi-line comment ("*/").
-func isEndOfMLComment(s []rune) bool {
-	return len(s) >= 2 && s[0] == '*' && s[1] == '/'
-}
-
-func isKeyword(s string) bool {
-	return keywords[s]
+// hasPrefix reports whether s starts with prefix.
+func hasPrefix(s []rune, prefix string) bool {
+	i := 0
+	for _, r := range prefix {
+		if i == len(s) || s[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
 }
 
 // startsWithLPareen rports whether s starts with arbitary amount of whitespace and a left parenthesis '('.
@@ -75,91 +83,78 @@ func startsWithLParen(s []rune) bool {
 	return i != len(s) && s[i] == '('
 }
 
-// searchEnd returns length of continuing nestedClass symbol and the changed newNestingLevel.
-func searchEnd(s []rune, nestingLevel, nestedClass int) (length, newNestingLevel int) {
-	length = 0
-	newNestingLevel = nestingLevel
-	if nestedClass == CString { // `-string
-		for length != len(s) && s[length] != '`' {
-			length++
-		}
-		if length != len(s) {
-			length++
-			newNestingLevel--
-		}
-	} else /* nestedClass == CComment */ {
-		for length != len(s) && !isEndOfMLComment(s[length:]) {
-			length++
-		}
-		if length != len(s) {
-			length += 2
-			newNestingLevel--
+// isLineStart reports whether line[:pos] only consists of whitespace.
+func isLineStart(line []rune, pos int) bool {
+	for i := 0; i != pos; i++ {
+		if !isWhitespace(line[i]) {
+			return false
 		}
 	}
-	return
+	return true
 }
 
-// Scan returns class (one of c- constants, see above) and length of symbol in the beginning of s.
-func Scan(s []rune, nestingLevel, nestedClass int) (class, length, newNestingLevel int) {
-	newNestingLevel = nestingLevel
-	c := s[0]
-	if nestingLevel != 0 {
-		class = nestedClass
-		length, newNestingLevel = searchEnd(s, nestingLevel, nestedClass)
-	} else if isWhitespace(c) {
-		class = CNone
-		length = 1
-	} else if isAlpha(c) {
-		length = 1
-		for length != len(s) && isAlphaNumeric(s[length]) {
-			length++
-		}
-		if isKeyword(string(s[:length])) {
-			class = CKeyword
-		} else if startsWithLParen(s[length:]) {
-			class = CProcCall
-		} else {
-			class = CIdent
-		}
-	} else if isNumeric(c) {
-		class = CNumber
-		length = 1
-		for length != len(s) && isNumeric(s[length]) {
-			length++
-		}
-	} else if c == '/' { // Start of single-line comment ("//") or multi-line comment ("/*")
-		if len(s) > 1 && s[1] == '/' {
-			class = CComment
-			length = len(s)
-		} else if len(s) > 1 && s[1] == '*' {
-			class = CComment
-			length = 2
-			for length != len(s) && !isEndOfMLComment(s[length:]) {
-				length++
-			}
-			if length != len(s) {
-				length += 2
-			} else {
-				newNestingLevel++
+// scanWord returns the length of the identifier at the beginning of s.
+func scanWord(s []rune) int {
+	length := 1
+	for length != len(s) && isAlphaNumeric(s[length]) {
+		length++
+	}
+	return length
+}
+
+// scanNumber returns the length of the number at the beginning of s. It takes digits, letters, dots and
+// underscores, so that hexadecimal numbers, exponents and suffixes are parts of the number.
+func scanNumber(s []rune) int {
+	length := 1
+	for length != len(s) && (isAlphaNumeric(s[length]) || s[length] == '.') {
+		length++
+	}
+	return length
+}
+
+// classifyWord returns the class of the identifier at the beginning of s of the given length.
+func classifyWord(s []rune, length int, keywords map[string]bool) int {
+	if keywords[string(s[:length])] {
+		return CKeyword
+	} else if startsWithLParen(s[length:]) {
+		return CProcCall
+	}
+	return CIdent
+}
+
+// scanQuoted returns the length of the string at the beginning of s that ends with quote, including both
+// quotes. A backslash escapes the next character if escapes is set. Reports false if s ends before the string.
+func scanQuoted(s []rune, quote string, escapes bool) (length int, closed bool) {
+	n := len([]rune(quote))
+	length, closed = scanRest(s[n:], quote, escapes)
+	return length + n, closed
+}
+
+// scanRest returns the length of the rest of a string up to and including the closing quote,
+// and whether the quote was found.
+func scanRest(s []rune, quote string, escapes bool) (length int, closed bool) {
+	for length != len(s) {
+		if escapes && s[length] == '\\' {
+			length += 2
+			if length > len(s) {
+				length = len(s)
 			}
+		} else if hasPrefix(s[length:], quote) {
+			return length + len([]rune(quote)), true
 		} else {
-			class = CNone
-			length = 1
-		}
-	} else if c == '"' || c == '\'' || c == '`' {
-		class = CString
-		length = 1
-		for length != len(s) && s[length] != c {
 			length++
 		}
-		if length != len(s) {
-			length++
-		} else if c == '`' {
-			newNestingLevel++
-		}
-	} else {
-		class = CNone
-		length = 1
 	}
-	return
+	return length, false
+}
+
+// scanUntil returns the length of s up to and including end, and whether end was found.
+func scanUntil(s []rune, end string) (length int, found bool) {
+	for length != len(s) && !hasPrefix(s[length:], end) {
+		length++
+	}
+	if length != len(s) {
+		return length + len([]rune(end)), true
+	}
+	return length, false
 }
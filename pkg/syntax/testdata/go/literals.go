@@ -0,0 +1,46 @@
+// Literals and identifiers that are easy to get wrong.
+package literals
+
+import "fmt"
+
+type größe[T ~int | ~float64] struct {
+	wert  T
+	länge int
+}
+
+const (
+	dec    = 1_000_000
+	hex    = 0x1F_ff
+	oct    = 0o755 + 0755
+	bin    = 0b1010_0101
+	float  = 3.14 + .5 + 1. + 1e9 + 6.02e+23 + 1_5.2_5e-1_0
+	hexFlt = 0x1p-2 + 0x1.8p+1 + 0x_1FFFp-16
+	imag   = 2i + 0.5i + 1e3i + 0x10i + 0b11i
+)
+
+var (
+	runes = []rune{'a', '\'', '\\', '\n', '\x7f', 'é', '\U0001F600', '\101', 'ä'}
+	strs  = []string{"a\"b", "tab\tend", "é\xff", "", `raw \n "quoted"`}
+	raw   = `first line
+second line with // no comment
+last`
+	χ2, π = 1.0, 3.14159
+)
+
+/* A block comment
+   over several lines */
+
+func (g *größe[T]) Länge() int { return g.länge }
+
+func main() {
+	var x uint8 = 0
+	x &^= 3
+	x <<= 1
+	ch := make(chan int, len(runes))
+	go func() { ch <- cap(strs) }()
+	v, ok := <-ch
+	fmt.Println(x, v, ok, raw, χ2*π, nil == any(nil), hexFlt/float, imag)
+	for i := range 10 {
+		_ = append([]int{}, []int{i}...)
+	}
+}
@@ -0,0 +1,21 @@
+package text
+
+// Clipboard is used by Cut, Copy and Paste commands.
+type Clipboard interface {
+	SetText(text string) error
+	Text() (string, error)
+}
+
+// MemoryClipboard is a clipboard that holds the text in memory, for texts that are not displayed.
+type MemoryClipboard struct {
+	text string
+}
+
+func (c *MemoryClipboard) SetText(text string) error {
+	c.text = text
+	return nil
+}
+
+func (c *MemoryClipboard) Text() (string, error) {
+	return c.text, nil
+}
@@ -0,0 +1,147 @@
+package text
+
+// AnyColor can be passed to FindColorRun instead of a color number to find regions of all colors.
+const AnyColor = -1
+
+// ColorRun is a colored region of the text from (LineFrom; CharFrom) to (LineTo; CharTo).
+// A region continues on the next line if the run covers the new line character and the next line starts
+// with the same color.
+type ColorRun struct {
+	Color              int
+	LineFrom, CharFrom int
+	LineTo, CharTo     int
+}
+
+// Colors returns the distinct colors of the runs of l in the order they appear, without color 0.
+func (l *Line) Colors() []int {
+	var colors []int
+	for r := l.runs; r != nil; r = r.next {
+		if r.color != 0 && r.length != 0 && !containsInt(colors, r.color) {
+			colors = append(colors, r.color)
+		}
+	}
+	return colors
+}
+
+func containsInt(a []int, x int) bool {
+	for _, y := range a {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
+
+// continuesRun reports whether the first run of line l continues a region from the previous line.
+func continuesRun(l *Line) bool {
+	if l.prev == nil || l.runs == nil {
+		return false
+	}
+	last := l.prev.runs
+	for last.next != nil {
+		last = last.next
+	}
+	return last.color == l.runs.color
+}
+
+// colorMatches reports whether a run of color runColor is searched for with clr.
+func colorMatches(runColor, clr int) bool {
+	if clr == AnyColor {
+		return runColor != 0
+	}
+	return runColor == clr
+}
+
+// lineRunStarts returns the regions of color clr that start in line l, with only their start filled in.
+// A region that only consists of the new line character is skipped, because it cannot be selected.
+func lineRunStarts(l *Line, lineNum, clr int) []ColorRun {
+	var runs []ColorRun
+	pos := 0
+	for r := l.runs; r != nil; r = r.next {
+		if colorMatches(r.color, clr) && r.length != 0 && (pos != 0 || !continuesRun(l)) &&
+			(pos != len(l.chars) || l.next != nil && continuesRun(l.next)) {
+			runs = append(runs, ColorRun{Color: r.color, LineFrom: lineNum, CharFrom: pos})
+		}
+		pos += r.length
+	}
+	return runs
+}
+
+// runEnd fills in the end of the region that starts at (run.LineFrom; run.CharFrom).
+func (t *TextImpl) runEnd(run *ColorRun) {
+	l, lineNum := t.LineByNum(run.LineFrom)
+	r, offset := l.FindRun(run.CharFrom)
+	end := run.CharFrom - offset + r.length
+	for end == len(l.chars)+1 && l.next != nil && continuesRun(l.next) {
+		l = l.next
+		lineNum++
+		end = l.runs.length
+	}
+	if end > len(l.chars) {
+		end = len(l.chars)
+	}
+	run.LineTo, run.CharTo = lineNum, end
+}
+
+// findColorRun returns the first region of color clr that starts after (lineNum; x), or the last one
+// that starts before it if backward. The search wraps around the document.
+func (t *TextImpl) findColorRun(lineNum, x, clr int, backward bool) (ColorRun, bool) {
+	line, lineNum := t.LineByNum(lineNum)
+	for i := 0; i <= t.lineCount; i++ {
+		runs := lineRunStarts(line, lineNum, clr)
+		if backward {
+			for j := len(runs) - 1; j >= 0; j-- {
+				if i != 0 || runs[j].CharFrom < x {
+					return runs[j], true
+				}
+			}
+			line = line.prev
+			lineNum--
+			if line == nil {
+				line, lineNum = t.last, t.lineCount
+			}
+		} else {
+			for _, run := range runs {
+				if i != 0 || run.CharFrom >= x {
+					return run, true
+				}
+			}
+			line = line.next
+			lineNum++
+			if line == nil {
+				line, lineNum = t.first, 1
+			}
+		}
+	}
+	return ColorRun{}, false
+}
+
+// CursorColor returns the color at the beginning of the selection, or at the cursor if nothing is selected.
+func (t *TextImpl) CursorColor() int {
+	lineNum, x := t.selectionStart()
+	line, _ := t.LineByNum(lineNum)
+	r, _ := line.FindRun(x)
+	if r == nil {
+		return 0
+	}
+	return r.color
+}
+
+// FindColorRun selects the next region of color clr (or of any color if clr = AnyColor) after the cursor, or the previous one before
+// the selection if backward, and places the cursor at its end. Reports whether there is such a region.
+func (t *TextImpl) FindColorRun(clr int, backward bool) bool {
+	lineNum, x := t.curLineNum, t.cursorX
+	if backward {
+		lineNum, x = t.selectionStart()
+	}
+	run, ok := t.findColorRun(lineNum, x, clr, backward)
+	if !ok {
+		return false
+	}
+	t.runEnd(&run)
+	line, _ := t.LineByNum(run.LineTo)
+	t.SetCurLine(line, run.LineTo)
+	t.SetCursorX(run.CharTo)
+	t.SetSelection(run.LineFrom, run.CharFrom, run.LineTo, run.CharTo)
+	return true
+}
@@ -0,0 +1,60 @@
+package text
+
+import (
+	"github.com/patrikaleksandryan/coloride/pkg/diff"
+)
+
+// Kinds of rows of a comparison
+const (
+	RowEqual        = iota // Lines are equal
+	RowColorChanged        // Lines have the same characters, but different colors
+	RowChanged             // Characters of the line have been changed
+	RowRemoved             // Line is only in the first text
+	RowAdded               // Line is only in the second text
+)
+
+// DiffRow is a row of a side by side comparison of two texts.
+type DiffRow struct {
+	Kind         int
+	LineA, LineB int // Line numbers, 0 if the row has no line on that side
+}
+
+// Compare compares two revisions of a text line by line. Lines are matched by their characters,
+// so that lines that have only been recolored are reported as RowColorChanged rather than as changed.
+func Compare(a, b *TextImpl) []DiffRow {
+	aLines, bLines := lineSlice(a.first), lineSlice(b.first)
+	var rows []DiffRow
+	i, j := 0, 0 // Next unmatched lines of a and b
+	for _, m := range append(diff.Strings(a.Strings(), b.Strings()), diff.Match{A: len(aLines), B: len(bLines)}) {
+		// Lines between matches have been changed, pair them in order
+		for ; i != m.A && j != m.B; i, j = i+1, j+1 {
+			rows = append(rows, DiffRow{Kind: RowChanged, LineA: i + 1, LineB: j + 1})
+		}
+		for ; i != m.A; i++ {
+			rows = append(rows, DiffRow{Kind: RowRemoved, LineA: i + 1})
+		}
+		for ; j != m.B; j++ {
+			rows = append(rows, DiffRow{Kind: RowAdded, LineB: j + 1})
+		}
+		if m.A != len(aLines) {
+			kind := RowEqual
+			if !sameColors(aLines[m.A], bLines[m.B]) {
+				kind = RowColorChanged
+			}
+			rows = append(rows, DiffRow{Kind: kind, LineA: m.A + 1, LineB: m.B + 1})
+		}
+		i, j = m.A+1, m.B+1
+	}
+	return rows
+}
+
+// sameColors reports whether two lines with the same characters have the same runs.
+func sameColors(a, b *Line) bool {
+	ca, cb := a.CharColors(), b.CharColors()
+	for k := range ca {
+		if ca[k] != cb[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []DiffRow
+	}{
+		{"a\nb", "a\nb", []DiffRow{{RowEqual, 1, 1}, {RowEqual, 2, 2}}},
+		{"a\nb ///1R", "a\nb ///1g", []DiffRow{{RowEqual, 1, 1}, {RowColorChanged, 2, 2}}},
+		{"a\nb ///1R", "a\nb ///1R 0 1g", []DiffRow{{RowEqual, 1, 1}, {RowColorChanged, 2, 2}}},
+		{"ab ///1R", "ab ///2R", []DiffRow{{RowColorChanged, 1, 1}}},
+		{"ab ///1R", "ab ///1R", []DiffRow{{RowEqual, 1, 1}}},
+		{"a\nb\nc", "a\nx\nc", []DiffRow{{RowEqual, 1, 1}, {RowChanged, 2, 2}, {RowEqual, 3, 3}}},
+		{"a\nb ///1R\nc", "a\nx ///1R\nc", []DiffRow{{RowEqual, 1, 1}, {RowChanged, 2, 2}, {RowEqual, 3, 3}}},
+		{"a\nb\nc", "a\nc", []DiffRow{{RowEqual, 1, 1}, {RowRemoved, 2, 0}, {RowEqual, 3, 2}}},
+		{"a\nc", "a\nb\nc", []DiffRow{{RowEqual, 1, 1}, {RowAdded, 0, 2}, {RowEqual, 2, 3}}},
+		{"a\nb\nc", "x\ny", []DiffRow{{RowChanged, 1, 1}, {RowChanged, 2, 2}, {RowRemoved, 3, 0}}},
+		{"a", "x\ny\nz", []DiffRow{{RowChanged, 1, 1}, {RowAdded, 0, 2}, {RowAdded, 0, 3}}},
+		{"a\nb ///1R\nc", "a\nc\nb ///1g", []DiffRow{{RowEqual, 1, 1}, {RowRemoved, 2, 0}, {RowEqual, 3, 2}, {RowAdded, 0, 3}}},
+	}
+	for _, tt := range tests {
+		got := Compare(loadText(t, tt.a), loadText(t, tt.b))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q, %q: rows are %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package text
+
+import "github.com/patrikaleksandryan/coloride/pkg/syntax"
+
+// Every line caches the state of the lexer at its end, so that highlighting can start at any line without
+// scanning the text from the beginning. The states of the first TextImpl.validStates lines are up to date,
+// edits lower this number to the line before the first changed one, and the states of the following lines
+// are computed again when they are needed.
+
+// charsChanged is called before the characters of lines [lineFrom; lineTo] change. It drops the
+// cached highlighting of these lines.
+func (t *TextImpl) charsChanged(lineFrom, lineTo int) {
+	t.version++
+	t.invalidateStates(lineFrom)
+	line, lineNum := t.LineByNum(lineFrom)
+	for line != nil && lineNum <= lineTo {
+		line.semantic = nil
+		line = line.next
+		lineNum++
+	}
+}
+
+// invalidateStates marks the lexer states of lines starting with lineNum as outdated.
+func (t *TextImpl) invalidateStates(lineNum int) {
+	if t.validStates > lineNum-1 {
+		t.validStates = max(lineNum-1, 0)
+	}
+}
+
+// startState returns the state of the lexer at the beginning of line, which has the number lineNum.
+func (t *TextImpl) startState(line *Line, lineNum int) syntax.State {
+	if line.prev == nil {
+		return 0
+	}
+	return t.endState(line.prev, lineNum-1)
+}
+
+// endState returns the state of the lexer at the end of line, which has the number lineNum.
+func (t *TextImpl) endState(line *Line, lineNum int) syntax.State {
+	if lineNum > t.validStates {
+		var state syntax.State
+		l, _ := t.LineByNum(t.validStates + 1)
+		if l.prev != nil {
+			state = l.prev.endState
+		}
+		for n := t.validStates + 1; n <= lineNum; n++ {
+			state = syntax.ScanLine(t.lexer, l.chars, state)
+			l.endState = state
+			l = l.next
+		}
+		t.validStates = lineNum
+	}
+	return line.endState
+}
+
+// Version returns a number that changes whenever the characters of the text change.
+func (t *TextImpl) Version() int {
+	return t.version
+}
+
+// SetSemantic attaches the result of semantic analysis to the lines of the text. The spans are ignored if
+// the text has changed since the given version, the function reports whether they have been used.
+func (t *TextImpl) SetSemantic(version int, spans []syntax.Span) bool {
+	if version != t.version {
+		return false
+	}
+	t.ClearSemantic()
+	line, lineNum := t.first, 1
+	for _, span := range spans {
+		for line != nil && lineNum < span.Line {
+			line = line.next
+			lineNum++
+		}
+		if line == nil {
+			break
+		}
+		line.semantic = append(line.semantic, span)
+	}
+	return true
+}
+
+// ClearSemantic removes the result of semantic analysis from all lines.
+func (t *TextImpl) ClearSemantic() {
+	for line := t.first; line != nil; line = line.next {
+		line.semantic = nil
+	}
+}
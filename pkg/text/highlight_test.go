@@ -0,0 +1,56 @@
+package text
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/patrikaleksandryan/coloride/pkg/syntax"
+)
+
+const highlightSrc = "package p\n\n/* A comment\nover lines */\nvar s = `raw\nstring /*`\n// x */ `\nvar t = \"/*\"\n"
+
+// checkStates fails the test if the cached lexer state at the end of a line differs from the state
+// found by scanning the text from the beginning.
+func checkStates(t *testing.T, txt *TextImpl, step int) {
+	t.Helper()
+	var state syntax.State
+	lineNum := 1
+	for l := txt.first; l != nil; l = l.next {
+		state = syntax.ScanLine(txt.lexer, l.chars, state)
+		if got := txt.endState(l, lineNum); got != state {
+			t.Fatalf("step %d: line %d %q ends in state %d, want %d\n%s", step, lineNum, string(l.chars), got, state,
+				writeString(t, txt))
+		}
+		lineNum++
+	}
+}
+
+func TestLexerStates(t *testing.T) {
+	fragments := []string{"/*", "*/", "`", "\n", "\"", "//", "x", "a\nb", "*/\n`"}
+	r := rand.New(rand.NewPCG(1, 2))
+	txt := loadText(t, highlightSrc)
+	for step := 0; step < 3000; step++ {
+		// Ask for the state of a random line first, so that only some of the states are up to date
+		line, lineNum := txt.LineByNum(1 + r.IntN(txt.lineCount))
+		txt.endState(line, lineNum)
+
+		line, lineNum = txt.LineByNum(1 + r.IntN(txt.lineCount))
+		txt.SetCurLine(line, lineNum)
+		txt.SetCursorX(r.IntN(len(line.chars) + 1))
+		switch r.IntN(4) {
+		case 0, 1:
+			txt.InsertText(fragments[r.IntN(len(fragments))])
+		case 2:
+			lineTo := min(lineNum+r.IntN(3), txt.lineCount)
+			last, _ := txt.LineByNum(lineTo)
+			txt.SetSelection(lineNum, txt.cursorX, lineTo, r.IntN(len(last.chars)+1))
+			txt.DeleteSelectedText()
+		case 3:
+			txt.HandleUndo()
+		}
+		if r.IntN(2) == 0 {
+			checkStates(t, txt, step)
+		}
+	}
+	checkStates(t, txt, -1)
+}
@@ -0,0 +1,288 @@
+package text
+
+const (
+	maxUndoSteps = 1000 // Older steps are forgotten
+)
+
+const (
+	// Kinds of edits
+
+	editOther  = iota // Any edit that is never merged with other edits
+	editTyping        // Single character typed; successive ones are grouped into words
+	editColor         // Colorization of the selection
+)
+
+// position is a cursor position in the text.
+type position struct {
+	lineNum int // 1-based
+	x       int // 0-based
+}
+
+// edit is a single undoable step. It holds copies of the lines the edit has replaced and
+// copies of the lines it has put instead of them.
+type edit struct {
+	kind         int
+	lineNum      int     // Number of the first affected line
+	before       []*Line // Copies of the affected lines before the edit
+	after        []*Line // Copies of the affected lines after the edit
+	cursorBefore position
+	cursorAfter  position
+	char         rune // The typed character if kind = editTyping
+}
+
+// unreachable is the saved edit when the saved state has been dropped from the undo stack. It is never on
+// the stack, so the text stays modified whatever is undone.
+var unreachable = &edit{}
+
+// History holds the undo and redo stacks of a text.
+type History struct {
+	undo, redo []*edit
+	saved      *edit // Top of undo stack at the moment the text was saved or loaded, or unreachable
+	pending    *edit // Edit being recorded
+	lineCount  int   // Line count of the text when pending edit started
+	depth      int   // Nesting level of beginEdit calls
+	disabled   bool  // Set while loading a file
+}
+
+// Clear empties both stacks.
+func (h *History) Clear() {
+	h.undo = nil
+	h.redo = nil
+	h.saved = nil
+	h.pending = nil
+	h.depth = 0
+}
+
+func (h *History) CanUndo() bool {
+	return len(h.undo) != 0
+}
+
+func (h *History) CanRedo() bool {
+	return len(h.redo) != 0
+}
+
+func (h *History) top() *edit {
+	if len(h.undo) == 0 {
+		return nil
+	}
+	return h.undo[len(h.undo)-1]
+}
+
+// markSaved remembers the current state as the one stored in a file.
+func (h *History) markSaved() {
+	h.saved = h.top()
+}
+
+// isModified reports whether the current state differs from the one stored in a file.
+func (h *History) isModified() bool {
+	return h.top() != h.saved
+}
+
+// push adds e to the undo stack, or merges it into the top edit if both are typing of the same word.
+func (h *History) push(e *edit) {
+	h.redo = nil
+	top := h.top()
+	if top != nil && top != h.saved && canMergeTyping(top, e) {
+		top.after = e.after
+		top.cursorAfter = e.cursorAfter
+		top.char = e.char
+		return
+	}
+	if len(h.undo) == maxUndoSteps {
+		// The state before the dropped step cannot be restored anymore, the state after it is the bottom of the stack
+		if h.saved == nil {
+			h.saved = unreachable
+		} else if h.saved == h.undo[0] {
+			h.saved = nil
+		}
+		h.undo = h.undo[1:]
+	}
+	h.undo = append(h.undo, e)
+}
+
+// canMergeTyping reports whether e continues the typing recorded in top.
+// A new step is started at the beginning of every word.
+func canMergeTyping(top, e *edit) bool {
+	return top.kind == editTyping && e.kind == editTyping &&
+		top.lineNum == e.lineNum && len(top.after) == 1 && len(e.before) == 1 &&
+		top.cursorAfter == e.cursorBefore &&
+		!(isWordChar(e.char) && !isWordChar(top.char))
+}
+
+func isWordChar(ch rune) bool {
+	return ch == '_' || '0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch > 127
+}
+
+// Text
+
+func (t *TextImpl) History() *History {
+	return &t.history
+}
+
+func (t *TextImpl) cursorPos() position {
+	return position{lineNum: t.curLineNum, x: t.cursorX}
+}
+
+// editRange returns the range of lines affected by typing: the selected lines or the current line.
+func (t *TextImpl) editRange() (lineFrom, lineTo int) {
+	if t.selected {
+		return t.selection.LineFrom, t.selection.LineTo
+	}
+	return t.curLineNum, t.curLineNum
+}
+
+// beginEdit starts recording of an edit that may change lines [lineFrom; lineTo] and insert new lines
+// after them. Calls may be nested, only the outermost one is recorded. Each call must be paired with endEdit.
+func (t *TextImpl) beginEdit(kind int, lineFrom, lineTo int) {
+	t.edits++
+	if kind != editColor {
+		t.charsChanged(lineFrom, lineTo)
+	}
+	h := &t.history
+	h.depth++
+	if h.depth != 1 || h.disabled {
+		return
+	}
+	if lineFrom < 1 {
+		lineFrom = 1
+	}
+	if lineTo > t.lineCount {
+		lineTo = t.lineCount
+	}
+	h.lineCount = t.lineCount
+	h.pending = &edit{
+		kind:         kind,
+		lineNum:      lineFrom,
+		before:       t.copyLines(lineFrom, lineTo-lineFrom+1),
+		cursorBefore: t.cursorPos(),
+	}
+}
+
+// beginTyping starts recording of an edit that types character ch.
+func (t *TextImpl) beginTyping(ch rune) {
+	kind := editTyping
+	if t.selected {
+		kind = editOther // Typing replaces the selection
+	}
+	lineFrom, lineTo := t.editRange()
+	t.beginEdit(kind, lineFrom, lineTo)
+	if t.history.pending != nil && t.history.depth == 1 {
+		t.history.pending.char = ch
+	}
+}
+
+// endEdit finishes recording of the edit started by beginEdit and puts it on the undo stack.
+func (t *TextImpl) endEdit() {
+	h := &t.history
+	h.depth--
+	if h.depth != 0 || h.pending == nil {
+		return
+	}
+	e := h.pending
+	h.pending = nil
+	count := len(e.before) + t.lineCount - h.lineCount
+	e.after = t.copyLines(e.lineNum, count)
+	e.cursorAfter = t.cursorPos()
+	if !linesEqual(e.before, e.after) {
+		h.push(e)
+		t.setEdited(true)
+	}
+}
+
+// copyLines returns copies of count lines starting with line lineNum.
+func (t *TextImpl) copyLines(lineNum, count int) []*Line {
+	lines := make([]*Line, 0, count)
+	line, _ := t.LineByNum(lineNum)
+	for line != nil && len(lines) != count {
+		lines = append(lines, line.Clone())
+		line = line.next
+	}
+	return lines
+}
+
+// replaceLines replaces count lines starting with line lineNum by copies of the given lines.
+func (t *TextImpl) replaceLines(lineNum, count int, lines []*Line) {
+	t.invalidateStates(lineNum)
+	t.version++
+	t.edits++
+	var prev *Line
+	next := t.first
+	if lineNum > 1 {
+		prev, _ = t.LineByNum(lineNum - 1)
+		next = prev.next
+	}
+	for i := 0; i != count && next != nil; i++ {
+		t.index.Remove(next)
+		next = next.next
+		t.lineCount--
+	}
+
+	for i, l := range lines {
+		l = l.Clone()
+		l.prev = prev
+		if prev == nil {
+			t.first = l
+		} else {
+			prev.next = l
+		}
+		t.index.Insert(lineNum+i, l)
+		prev = l
+		t.lineCount++
+	}
+	if prev == nil {
+		t.first = next
+	} else {
+		prev.next = next
+	}
+	if next == nil {
+		t.last = prev
+	} else {
+		next.prev = prev
+	}
+}
+
+// restoreCursor places the cursor at the given position and resets the selection.
+func (t *TextImpl) restoreCursor(pos position) {
+	t.ClearSelection()
+	t.curLine, t.curLineNum = t.LineByNum(pos.lineNum)
+	t.SetCursorX(pos.x)
+	t.MoveToCursor()
+	t.clampScroll()
+}
+
+func (t *TextImpl) HandleUndo() {
+	h := &t.history
+	e := h.top()
+	if e != nil {
+		h.undo = h.undo[:len(h.undo)-1]
+		h.redo = append(h.redo, e)
+		t.replaceLines(e.lineNum, len(e.after), e.before)
+		t.restoreCursor(e.cursorBefore)
+		t.setEdited(h.isModified())
+	}
+}
+
+func (t *TextImpl) HandleRedo() {
+	h := &t.history
+	if len(h.redo) != 0 {
+		e := h.redo[len(h.redo)-1]
+		h.redo = h.redo[:len(h.redo)-1]
+		h.undo = append(h.undo, e)
+		t.replaceLines(e.lineNum, len(e.before), e.after)
+		t.restoreCursor(e.cursorAfter)
+		t.setEdited(h.isModified())
+	}
+}
+
+// linesEqual reports whether two lists of lines have the same characters, runs and new line types.
+func linesEqual(a, b []*Line) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
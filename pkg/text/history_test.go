@@ -0,0 +1,86 @@
+package text
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUndoBeyondLimitKeepsModified(t *testing.T) {
+	txt := NewText().(*TextImpl)
+	const edits = maxUndoSteps + 5
+	for i := 0; i < edits; i++ {
+		txt.HandleEnter()
+	}
+	for txt.History().CanUndo() {
+		txt.HandleUndo()
+	}
+	if txt.lineCount != edits-maxUndoSteps+1 {
+		t.Fatalf("line count after undo = %d, want %d", txt.lineCount, edits-maxUndoSteps+1)
+	}
+	if !txt.edited {
+		t.Error("text is unmodified after undo, but the saved state was dropped from the history")
+	}
+}
+
+func TestUndoToSavedStateAtBottom(t *testing.T) {
+	txt := NewText().(*TextImpl)
+	txt.HandleEnter()
+	txt.history.markSaved()
+	txt.setEdited(false)
+	for i := 0; i < maxUndoSteps; i++ {
+		txt.HandleEnter()
+	}
+	for txt.History().CanUndo() {
+		txt.HandleUndo()
+	}
+	if txt.edited {
+		t.Error("text is modified after undoing to the saved state")
+	}
+}
+
+func TestUndoRedoRestoresText(t *testing.T) {
+	txt := NewText().(*TextImpl)
+	err := txt.Load(strings.NewReader("package main ///8:4R\n\nfunc f() {\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := writeString(t, txt)
+	txt.SetCurLine(txt.LineByNum(2))
+	for _, r := range "hello world" {
+		txt.HandleChar(r)
+	}
+	txt.HandleEnter()
+	txt.InsertText("a\nb\nc")
+	txt.SetSelection(1, 0, 3, 2)
+	txt.ColorizeSelection(6)
+	txt.SetSelection(1, 3, 4, 1)
+	txt.HandleBackspace()
+	changed := writeString(t, txt)
+
+	for txt.History().CanUndo() {
+		txt.HandleUndo()
+	}
+	if got := writeString(t, txt); got != orig {
+		t.Errorf("after undo:\n%q\nwant\n%q", got, orig)
+	}
+	if txt.edited {
+		t.Error("text is modified after undoing all edits")
+	}
+	for txt.History().CanRedo() {
+		txt.HandleRedo()
+	}
+	if got := writeString(t, txt); got != changed {
+		t.Errorf("after redo:\n%q\nwant\n%q", got, changed)
+	}
+}
+
+// writeString returns the text as it would be saved.
+func writeString(t *testing.T, txt *TextImpl) string {
+	t.Helper()
+	var b strings.Builder
+	err := txt.Write(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
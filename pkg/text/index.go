@@ -0,0 +1,141 @@
+package text
+
+import (
+	"math/rand/v2"
+)
+
+// lineNode is a node of the line index.
+type lineNode struct {
+	line                *Line
+	parent, left, right *lineNode
+	size                int // Number of lines in the subtree
+	priority            uint32
+}
+
+// lineIndex is a balanced binary tree (treap with implicit keys) built over the lines of a document.
+// In-order traversal of the tree gives the lines in the document order, which allows to find a line
+// by its number and a number of a line in O(log n). The lines themselves stay linked in a list.
+type lineIndex struct {
+	root *lineNode
+}
+
+func nodeSize(n *lineNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// update restores the size of n and the parent links of its children.
+func (n *lineNode) update() {
+	n.size = 1 + nodeSize(n.left) + nodeSize(n.right)
+	if n.left != nil {
+		n.left.parent = n
+	}
+	if n.right != nil {
+		n.right.parent = n
+	}
+}
+
+// splitNodes splits tree n in two trees, the first one holding count first lines, and the second one the rest.
+func splitNodes(n *lineNode, count int) (left, right *lineNode) {
+	if n == nil {
+		return nil, nil
+	}
+	if nodeSize(n.left) < count {
+		n.right, right = splitNodes(n.right, count-nodeSize(n.left)-1)
+		n.update()
+		left = n
+	} else {
+		left, n.left = splitNodes(n.left, count)
+		n.update()
+		right = n
+	}
+	return
+}
+
+// mergeNodes joins two trees, placing all lines of tree b after the lines of tree a.
+func mergeNodes(a, b *lineNode) *lineNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.priority > b.priority {
+		a.right = mergeNodes(a.right, b)
+		a.update()
+		return a
+	}
+	b.left = mergeNodes(a, b.left)
+	b.update()
+	return b
+}
+
+func (x *lineIndex) setRoot(root *lineNode) {
+	x.root = root
+	if root != nil {
+		root.parent = nil
+	}
+}
+
+// Reset rebuilds the index from the list of lines starting with first.
+func (x *lineIndex) Reset(first *Line) {
+	x.root = nil
+	num := 1
+	for l := first; l != nil; l = l.next {
+		x.Insert(num, l)
+		num++
+	}
+}
+
+// Count returns the number of indexed lines.
+func (x *lineIndex) Count() int {
+	return nodeSize(x.root)
+}
+
+// Insert adds line l to the index, so that it gets number lineNum.
+func (x *lineIndex) Insert(lineNum int, l *Line) {
+	l.node = &lineNode{line: l, size: 1, priority: rand.Uint32()}
+	left, right := splitNodes(x.root, lineNum-1)
+	x.setRoot(mergeNodes(mergeNodes(left, l.node), right))
+}
+
+// Remove deletes line l from the index.
+func (x *lineIndex) Remove(l *Line) {
+	lineNum := x.LineNum(l)
+	left, right := splitNodes(x.root, lineNum-1)
+	_, right = splitNodes(right, 1)
+	x.setRoot(mergeNodes(left, right))
+	l.node = nil
+}
+
+// Line returns the line with the given 1-based number, or nil if there is no such line.
+func (x *lineIndex) Line(lineNum int) *Line {
+	n := x.root
+	for n != nil {
+		leftSize := nodeSize(n.left)
+		if lineNum <= leftSize {
+			n = n.left
+		} else if lineNum == leftSize+1 {
+			return n.line
+		} else {
+			lineNum -= leftSize + 1
+			n = n.right
+		}
+	}
+	return nil
+}
+
+// LineNum returns the 1-based number of line l. l must be in the index.
+func (x *lineIndex) LineNum(l *Line) int {
+	n := l.node
+	lineNum := nodeSize(n.left) + 1
+	for n.parent != nil {
+		if n == n.parent.right {
+			lineNum += nodeSize(n.parent.left) + 1
+		}
+		n = n.parent
+	}
+	return lineNum
+}
@@ -0,0 +1,135 @@
+package text
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+const benchLines = 50000 // Size of the generated documents
+
+// generatedText returns a text of lineCount lines of Go-like code with some color comments.
+func generatedText(tb testing.TB, lineCount int) *TextImpl {
+	tb.Helper()
+	var b strings.Builder
+	for i := 1; i <= lineCount; i++ {
+		if i%10 == 0 {
+			fmt.Fprintf(&b, "\tx%d := compute(%d, \"line\") ///2 3R\n", i, i)
+		} else {
+			fmt.Fprintf(&b, "\tx%d := compute(%d, \"line\")\n", i, i)
+		}
+	}
+	txt := NewText().(*TextImpl)
+	err := txt.Load(strings.NewReader(b.String()))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return txt
+}
+
+// linearLineByNum finds a line by walking the list from the first line, as it was done before the index.
+func linearLineByNum(t *TextImpl, lineNum int) *Line {
+	l := t.first
+	for i := 1; i != lineNum && l.next != nil; i++ {
+		l = l.next
+	}
+	return l
+}
+
+func TestLineIndexMatchesList(t *testing.T) {
+	txt := generatedText(t, 300)
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 200; i++ {
+		line, lineNum := txt.LineByNum(r.IntN(txt.lineCount) + 1)
+		txt.SetCurLine(line, lineNum)
+		switch r.IntN(3) {
+		case 0:
+			txt.HandleEnter()
+		case 1:
+			txt.HandleBackspace()
+		case 2:
+			txt.InsertText("a\nb")
+		}
+	}
+	if txt.index.Count() != txt.lineCount {
+		t.Fatalf("index has %d lines, text has %d", txt.index.Count(), txt.lineCount)
+	}
+	lineNum := 1
+	for l := txt.first; l != nil; l = l.next {
+		if got, _ := txt.LineByNum(lineNum); got != l {
+			t.Fatalf("LineByNum(%d) returns a wrong line", lineNum)
+		}
+		if got := txt.LineNum(l); got != lineNum {
+			t.Fatalf("LineNum of line %d = %d", lineNum, got)
+		}
+		lineNum++
+	}
+}
+
+func BenchmarkLineByNum(b *testing.B) {
+	txt := generatedText(b, benchLines)
+	lineNums := make([]int, 1000)
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range lineNums {
+		lineNums[i] = r.IntN(benchLines) + 1
+	}
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			txt.LineByNum(lineNums[i%len(lineNums)])
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			linearLineByNum(txt, lineNums[i%len(lineNums)])
+		}
+	})
+}
+
+// BenchmarkReaderNearEnd reads a screen of text near the end of the document, as the editor does on every frame.
+func BenchmarkReaderNearEnd(b *testing.B) {
+	const charW, charH = 8, 16
+	const pageLines = 50
+	txt := generatedText(b, benchLines)
+	view := NewView(txt, 800, pageLines*charH, charW, charH)
+	view.ScrollTo(0, (benchLines-pageLines)*charH)
+	r := view.Reader()
+	r.TopLine() // Fills the lexer state cache
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r.TopLine()
+			readPage(r, pageLines)
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			linearTopLine(r)
+			readPage(r, pageLines)
+		}
+	})
+}
+
+// linearTopLine does what Reader.TopLine does, but finds the top line by walking the list.
+func linearTopLine(r *Reader) {
+	r.curLineNum = r.view.scrollY/r.view.charH + 1
+	r.curLine = linearLineByNum(r.text, r.curLineNum)
+	r.column = 0
+	r.symbolEnd = 0
+	r.symbolClass = 0
+	r.lexer = r.text.lexer
+	r.state = r.text.startState(r.curLine, r.curLineNum)
+}
+
+// readPage reads lineCount lines with the reader, character by character.
+func readPage(r *Reader, lineCount int) {
+	for n := 0; n != lineCount; n++ {
+		_, ok := r.FirstChar()
+		for ok {
+			_, ok = r.NextChar()
+		}
+		if r.NextLine() == -1 {
+			break
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package text
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/patrikaleksandryan/coloride/pkg/charset"
+	"github.com/patrikaleksandryan/coloride/pkg/palette"
+	"github.com/patrikaleksandryan/coloride/pkg/scanner"
+	"github.com/patrikaleksandryan/coloride/pkg/syntax"
+)
+
+// journal is a text as it is kept for crash recovery. Unlike a saved file, it holds the runs, the line endings
+// and the whitespace before color comments exactly, and does not depend on the color comment syntax.
+type journal struct {
+	Encoding     charset.Encoding    `json:"encoding"`
+	NewLineType  int                 `json:"newLineType"`
+	ColorComment syntax.ColorComment `json:"colorComment"`
+	Lines        []journalLine       `json:"lines"`
+}
+
+type journalLine struct {
+	Chars       string       `json:"chars"`
+	Spaces      string       `json:"spaces,omitempty"`
+	RawBytes    [][2]int     `json:"rawBytes,omitempty"` // Positions in Chars and values of the bytes of invalid UTF-8
+	NewLineType int          `json:"newLineType"`
+	Runs        []journalRun `json:"runs"`
+}
+
+type journalRun struct {
+	Length int    `json:"length"`
+	Color  string `json:"color,omitempty"` // Name of the color in the palette, "" for no color
+}
+
+// Edits returns a number that is incremented on every edit, including changes of colors and line endings.
+func (t *TextImpl) Edits() int {
+	return t.edits
+}
+
+// WriteJournal writes the text to w in the format of recovery journals, see ReadJournal.
+func (t *TextImpl) WriteJournal(w io.Writer) error {
+	j := journal{
+		Encoding:     t.encoding,
+		NewLineType:  t.newLineType,
+		ColorComment: t.colorComment,
+		Lines:        make([]journalLine, 0, t.lineCount),
+	}
+	for l := t.first; l != nil; l = l.next {
+		jl := journalLine{
+			Chars:       string(l.chars),
+			Spaces:      string(l.spaces),
+			NewLineType: l.NewLineType,
+		}
+		for i, r := range l.chars {
+			if b, ok := charset.RawByteOf(r); ok {
+				jl.RawBytes = append(jl.RawBytes, [2]int{i, int(b)})
+			}
+		}
+		for r := l.runs; r != nil; r = r.next {
+			jr := journalRun{Length: r.length}
+			if r.color != 0 {
+				jr.Color = palette.Get(r.color).Name
+			}
+			jl.Runs = append(jl.Runs, jr)
+		}
+		j.Lines = append(j.Lines, jl)
+	}
+	return json.NewEncoder(w).Encode(&j)
+}
+
+// ReadJournal replaces the contents of the text with a journal written by WriteJournal.
+// The text is marked as edited, since the journal holds unsaved changes.
+func (t *TextImpl) ReadJournal(r io.Reader) error {
+	var j journal
+	err := json.NewDecoder(r).Decode(&j)
+	if err != nil {
+		return fmt.Errorf("decode journal: %w", err)
+	}
+	if len(j.Lines) == 0 || !validNewLineType(j.NewLineType) {
+		return errors.New("invalid journal")
+	}
+	lines := make([]*Line, len(j.Lines))
+	for i, jl := range j.Lines {
+		l, err := jl.line()
+		if err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+		lines[i] = l
+	}
+
+	t.Clear()
+	t.encoding, t.newLineType, t.colorComment = j.Encoding, j.NewLineType, j.ColorComment
+	t.history.disabled = true
+	t.replaceLines(1, 1, lines)
+	t.history.disabled = false
+	t.MoveToBeginning()
+	t.setEdited(true)
+	return nil
+}
+
+// line returns the line kept in the journal. The lengths of its runs must add up to the length of the line.
+func (jl *journalLine) line() (*Line, error) {
+	if !validNewLineType(jl.NewLineType) || len(jl.Runs) == 0 {
+		return nil, errors.New("invalid line")
+	}
+	l := &Line{
+		chars:       []rune(jl.Chars),
+		spaces:      []rune(jl.Spaces),
+		NewLineType: jl.NewLineType,
+	}
+	for _, raw := range jl.RawBytes {
+		pos, b := raw[0], raw[1]
+		if pos < 0 || pos >= len(l.chars) || b < 0x80 || b > 0xFF {
+			return nil, errors.New("invalid raw byte")
+		}
+		l.chars[pos] = charset.RawByte(byte(b))
+	}
+	length := 0
+	var last *Run
+	for _, jr := range jl.Runs {
+		if jr.Length <= 0 {
+			return nil, errors.New("invalid run")
+		}
+		r := &Run{length: jr.Length}
+		if jr.Color != "" {
+			r.color = palette.Resolve(jr.Color)
+		}
+		if last == nil {
+			l.runs = r
+		} else {
+			last.next = r
+		}
+		last = r
+		length += jr.Length
+	}
+	if length != len(l.chars)+1 {
+		return nil, errors.New("runs do not match the characters")
+	}
+	return l, nil
+}
+
+func validNewLineType(newLineType int) bool {
+	return newLineType == scanner.LF || newLineType == scanner.CRLF || newLineType == scanner.CR
+}
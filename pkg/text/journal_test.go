@@ -0,0 +1,62 @@
+package text
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/patrikaleksandryan/coloride/pkg/scanner"
+)
+
+func TestJournalRoundTrip(t *testing.T) {
+	src := "a := 1  ///2 2R\r\nb := 2\rc := \"x\"\t\t///5 3g\n\nd()\r\n"
+	txt := loadString(t, "a.go", src)
+	txt.SetNewLineType(scanner.CRLF)
+
+	var b bytes.Buffer
+	err := txt.WriteJournal(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered := NewText().(*TextImpl)
+	err = recovered.ReadJournal(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := writeString(t, recovered); got != src {
+		t.Errorf("recovered as %q, want %q", got, src)
+	}
+	if recovered.newLineType != scanner.CRLF {
+		t.Errorf("new line type is %d, want %d", recovered.newLineType, scanner.CRLF)
+	}
+	if recovered.lineCount != txt.lineCount {
+		t.Fatalf("%d lines recovered, want %d", recovered.lineCount, txt.lineCount)
+	}
+	for l, r := txt.first, recovered.first; l != nil; l, r = l.next, r.next {
+		if !l.Equal(r) || string(l.spaces) != string(r.spaces) {
+			t.Errorf("line %q is recovered as %q", string(l.chars), string(r.chars))
+		}
+	}
+	if !recovered.edited {
+		t.Error("the recovered text is not marked as edited")
+	}
+}
+
+func TestReadInvalidJournal(t *testing.T) {
+	journals := []string{
+		"",
+		"{}",
+		`{"newLineType":0,"lines":[{"chars":"ab","newLineType":0,"runs":[{"length":2}]}]}`,
+		`{"newLineType":0,"lines":[{"chars":"ab","newLineType":7,"runs":[{"length":3}]}]}`,
+		`{"newLineType":0,"lines":[{"chars":"ab","newLineType":0,"runs":[{"length":3}],"rawBytes":[[5,255]]}]}`,
+	}
+	for _, j := range journals {
+		txt := loadString(t, "a.go", "keep")
+		if err := txt.ReadJournal(bytes.NewReader([]byte(j))); err == nil {
+			t.Errorf("%q is read", j)
+		}
+		if got := writeString(t, txt); got != "keep" {
+			t.Errorf("%q: text is changed to %q", j, got)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package text
 import (
 	"github.com/patrikaleksandryan/coloride/pkg/colorcode"
 	"github.com/patrikaleksandryan/coloride/pkg/scanner"
+	"github.com/patrikaleksandryan/coloride/pkg/syntax"
 )
 
 // Run holds color attributes of a run of characters in a line.
@@ -19,6 +20,9 @@ type Line struct {
 	NewLineType int // One of New Line Type constants in scanner.go
 	runs        *Run
 	prev, next  *Line
+	node        *lineNode     // Node of the line in the line index of the text
+	endState    syntax.State  // State of the lexer at the end of the line, see TextImpl.validStates
+	semantic    []syntax.Span // Identifiers classified by semantic analysis, sorted by column
 }
 
 // Line
@@ -42,6 +46,42 @@ func (l *Line) Next() *Line {
 	return l.next
 }
 
+// Clone returns a deep copy of the line that is not linked to any other line.
+func (l *Line) Clone() *Line {
+	c := &Line{
+		chars:       append([]rune(nil), l.chars...),
+		spaces:      append([]rune(nil), l.spaces...),
+		colorCode:   append([]rune(nil), l.colorCode...),
+		NewLineType: l.NewLineType,
+	}
+	var last *Run
+	for r := l.runs; r != nil; r = r.next {
+		newR := &Run{length: r.length, color: r.color}
+		if last == nil {
+			c.runs = newR
+		} else {
+			last.next = newR
+		}
+		last = newR
+	}
+	return c
+}
+
+// Equal reports whether both lines have the same characters, runs and new line type.
+func (l *Line) Equal(other *Line) bool {
+	if l.NewLineType != other.NewLineType || string(l.chars) != string(other.chars) {
+		return false
+	}
+	r, r2 := l.runs, other.runs
+	for r != nil && r2 != nil {
+		if r.length != r2.length || r.color != r2.color {
+			return false
+		}
+		r, r2 = r.next, r2.next
+	}
+	return r == nil && r2 == nil
+}
+
 func (l *Line) DeleteChar(pos int) {
 	l.chars = append(l.chars[:pos], l.chars[pos+1:]...)
 	r, _ := l.FindRun(pos)
@@ -231,6 +271,65 @@ func (l *Line) DeleteRange(from, to int) {
 	}
 }
 
+// InsertRange inserts characters s at the given position as a run of the given color.
+func (l *Line) InsertRange(pos int, s []rune, color int) {
+	if len(s) != 0 {
+		l.CutRun(pos)
+		newR := &Run{length: len(s), color: color}
+		if pos == 0 {
+			newR.next = l.runs
+			l.runs = newR
+		} else {
+			prev, _ := l.FindRun(pos - 1)
+			newR.next = prev.next
+			prev.next = newR
+		}
+		l.NormalizeRuns()
+
+		l.chars = append(l.chars[:pos], append(append([]rune(nil), s...), l.chars[pos:]...)...)
+	}
+}
+
+// ReplaceRange replaces characters in the given range [from; to) with s.
+// The new characters get the color of the first replaced character, so the colors around are kept.
+func (l *Line) ReplaceRange(from, to int, s []rune) {
+	r, _ := l.FindRun(from)
+	color := r.color
+	l.DeleteRange(from, to)
+	l.InsertRange(from, s, color)
+}
+
+// CharColors returns the colors of all characters of the line, including the new line character.
+func (l *Line) CharColors() []int {
+	colors := make([]int, 0, len(l.chars)+1)
+	for r := l.runs; r != nil; r = r.next {
+		for i := 0; i != r.length; i++ {
+			colors = append(colors, r.color)
+		}
+	}
+	return colors
+}
+
+// SetCharColors rebuilds the runs of the line from the colors of its characters.
+// colors must hold len(l.chars) + 1 elements, the last one being the color of the new line character.
+func (l *Line) SetCharColors(colors []int) {
+	l.runs = nil
+	var last *Run
+	for _, color := range colors {
+		if last != nil && last.color == color {
+			last.length++
+		} else {
+			r := &Run{length: 1, color: color}
+			if last == nil {
+				l.runs = r
+			} else {
+				last.next = r
+			}
+			last = r
+		}
+	}
+}
+
 // ApplyColorCode parses l.colorCode and applies the instructions as Colorize commands.
 func (l *Line) ApplyColorCode() {
 	s := colorcode.NewScanner(l.colorCode)
@@ -240,10 +339,10 @@ func (l *Line) ApplyColorCode() {
 		switch s.Sym {
 		case colorcode.Number:
 			column += s.Number
-		case colorcode.NumberedLetter:
+		case colorcode.NumberedLetter, colorcode.NumberedName:
 			l.Colorize(s.Color(), column, column+s.Number)
 			column += s.Number
-		case colorcode.Letter:
+		case colorcode.Letter, colorcode.Name:
 			l.Colorize(s.Color(), column, len(l.chars)+1)
 		}
 		s.Scan()
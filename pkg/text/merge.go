@@ -0,0 +1,214 @@
+package text
+
+import (
+	"github.com/patrikaleksandryan/coloride/pkg/diff"
+)
+
+// Merge merges the changes made in two revisions a and b of the common ancestor base into a new text,
+// like diff3 does. Lines are compared without their color comments, so that runs are merged separately:
+// a color change in one revision is kept even if the other one changed the same line, as long as
+// the changes do not touch the same characters. Conflicts are written with the usual conflict markers
+// labeled labelA and labelB, and their number is returned.
+func Merge(base, a, b *TextImpl, labelA, labelB string) (*TextImpl, int) {
+	m := &merger{labelA: labelA, labelB: labelB}
+	o, aLines, bLines := lineSlice(base.first), lineSlice(a.first), lineSlice(b.first)
+	aOf := matchedLines(diff.Strings(base.Strings(), a.Strings()), len(o))
+	bOf := matchedLines(diff.Strings(base.Strings(), b.Strings()), len(o))
+
+	i, j, k := 0, 0, 0 // Next lines of base, a and b after the last stable line
+	for {
+		// Stable lines are equal in all three revisions
+		s := i
+		for s != len(o) && (aOf[s] == -1 || bOf[s] == -1) {
+			s++
+		}
+		aEnd, bEnd := len(aLines), len(bLines)
+		if s != len(o) {
+			aEnd, bEnd = aOf[s], bOf[s]
+		}
+		if s != i || aEnd != j || bEnd != k {
+			m.mergeChunk(o[i:s], aLines[j:aEnd], bLines[k:bEnd])
+		}
+		if s == len(o) {
+			break
+		}
+		m.mergeStable(o[s], aLines[aEnd], bLines[bEnd])
+		i, j, k = s+1, aEnd+1, bEnd+1
+	}
+
+	if len(m.lines) == 0 {
+		m.lines = append(m.lines, NewLine())
+	}
+	t := NewText().(*TextImpl)
+	t.lexer, t.colorComment, t.encoding = a.lexer, a.colorComment, a.encoding
+	t.newLineType = a.newLineType
+	t.history.disabled = true
+	t.replaceLines(1, 1, m.lines)
+	t.history.disabled = false
+	t.MoveToBeginning()
+	return t, m.conflicts
+}
+
+type merger struct {
+	lines          []*Line
+	conflicts      int
+	labelA, labelB string
+}
+
+// matchedLines returns for each of n lines of the first sequence the number of the matched line of
+// the second one, or -1.
+func matchedLines(matches []diff.Match, n int) []int {
+	of := make([]int, n)
+	for i := range of {
+		of[i] = -1
+	}
+	for _, m := range matches {
+		of[m.A] = m.B
+	}
+	return of
+}
+
+// mergeStable merges three revisions of a line with the same characters.
+func (m *merger) mergeStable(o, a, b *Line) {
+	colors, ok := mergeColors(o.CharColors(), a.CharColors(), b.CharColors())
+	if !ok {
+		m.conflict([]*Line{a}, []*Line{b})
+		return
+	}
+	l := a.Clone()
+	l.SetCharColors(colors)
+	if string(a.spaces) == string(o.spaces) {
+		l.spaces = append([]rune(nil), b.spaces...)
+	}
+	if a.NewLineType == o.NewLineType {
+		l.NewLineType = b.NewLineType
+	}
+	m.lines = append(m.lines, l)
+}
+
+// mergeChunk merges lines that have been changed in a, in b or in both.
+func (m *merger) mergeChunk(o, a, b []*Line) {
+	switch {
+	case linesEqual(a, o):
+		m.lines = append(m.lines, b...)
+	case linesEqual(b, o), linesEqual(a, b):
+		m.lines = append(m.lines, a...)
+	case sameChars(a, o) && len(b) == len(o):
+		m.applyColorChanges(o, a, b, false)
+	case sameChars(b, o) && len(a) == len(o):
+		m.applyColorChanges(o, b, a, true)
+	case sameChars(a, b):
+		m.mergeSameChars(a, b)
+	default:
+		m.conflict(a, b)
+	}
+}
+
+// applyColorChanges merges lines that have only been recolored in one revision and edited line by line
+// in the other one. recoloredIsB tells which revision is which for the conflict markers.
+func (m *merger) applyColorChanges(o, recolored, edited []*Line, recoloredIsB bool) {
+	lines := make([]*Line, len(edited))
+	for i := range edited {
+		l, ok := applyLineColorChanges(o[i], recolored[i], edited[i])
+		if !ok {
+			if recoloredIsB {
+				m.conflict(edited, recolored)
+			} else {
+				m.conflict(recolored, edited)
+			}
+			return
+		}
+		lines[i] = l
+	}
+	m.lines = append(m.lines, lines...)
+}
+
+// applyLineColorChanges gives the characters of edited that have been kept from base the colors
+// they have in recolored, which has the same characters as base. Reports false if such a character
+// was recolored differently in edited too.
+func applyLineColorChanges(base, recolored, edited *Line) (*Line, bool) {
+	baseColors, newColors, colors := base.CharColors(), recolored.CharColors(), edited.CharColors()
+	matches := append(diff.Runes(base.chars, edited.chars), diff.Match{A: len(base.chars), B: len(edited.chars)})
+	for _, match := range matches {
+		was, now := baseColors[match.A], newColors[match.A]
+		if was != now {
+			if colors[match.B] != was && colors[match.B] != now {
+				return nil, false
+			}
+			colors[match.B] = now
+		}
+	}
+	l := edited.Clone()
+	l.SetCharColors(colors)
+	if string(edited.spaces) == string(base.spaces) {
+		l.spaces = append([]rune(nil), recolored.spaces...)
+	}
+	return l, true
+}
+
+// mergeSameChars merges lines that have been changed the same way in both revisions, but may differ in color.
+func (m *merger) mergeSameChars(a, b []*Line) {
+	lines := make([]*Line, len(a))
+	for i := range a {
+		colors, ok := mergeColors(nil, a[i].CharColors(), b[i].CharColors())
+		if !ok {
+			m.conflict(a, b)
+			return
+		}
+		lines[i] = a[i].Clone()
+		lines[i].SetCharColors(colors)
+	}
+	m.lines = append(m.lines, lines...)
+}
+
+// mergeColors merges the colors of the characters of two revisions of a line. If base is nil, the colors
+// must be equal. Reports false if a character has been recolored differently in both revisions.
+func mergeColors(base, a, b []int) ([]int, bool) {
+	colors := make([]int, len(a))
+	for i := range a {
+		switch {
+		case a[i] == b[i]:
+			colors[i] = a[i]
+		case base != nil && a[i] == base[i]:
+			colors[i] = b[i]
+		case base != nil && b[i] == base[i]:
+			colors[i] = a[i]
+		default:
+			return nil, false
+		}
+	}
+	return colors, true
+}
+
+// conflict appends the conflicting lines of both revisions between conflict markers.
+func (m *merger) conflict(a, b []*Line) {
+	m.conflicts++
+	m.marker("<<<<<<< " + m.labelA)
+	m.lines = append(m.lines, a...)
+	m.marker("=======")
+	m.lines = append(m.lines, b...)
+	m.marker(">>>>>>> " + m.labelB)
+}
+
+func (m *merger) marker(s string) {
+	l := NewLine()
+	l.chars = []rune(s)
+	l.runs.length = len(l.chars) + 1
+	if len(m.lines) != 0 {
+		l.NewLineType = m.lines[len(m.lines)-1].NewLineType
+	}
+	m.lines = append(m.lines, l)
+}
+
+// sameChars reports whether two groups of lines have the same characters, regardless of colors.
+func sameChars(a, b []*Line) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if string(a[i].chars) != string(b[i].chars) {
+			return false
+		}
+	}
+	return true
+}
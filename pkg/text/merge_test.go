@@ -0,0 +1,102 @@
+package text
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name          string
+		base, a, b    string
+		want          string
+		wantConflicts int
+	}{
+		{
+			name: "colors on different lines",
+			base: "a := 1\nb := 2\n",
+			a:    "a := 1 ///1R\nb := 2\n",
+			b:    "a := 1\nb := 2 ///1g\n",
+			want: "a := 1 ///1R\nb := 2 ///1g\n",
+		},
+		{
+			name: "colors of different characters of a line",
+			base: "abcd\n",
+			a:    "abcd ///1 1R\n",
+			b:    "abcd ///3 1g\n",
+			want: "abcd ///1 1R 1 1g\n",
+		},
+		{
+			name: "same colors in both",
+			base: "abcd\n",
+			a:    "abcd ///1 1R\n",
+			b:    "abcd ///1 1R\n",
+			want: "abcd ///1 1R\n",
+		},
+		{
+			name:          "colors of the same characters",
+			base:          "x := 1\n",
+			a:             "x := 1 ///1R\n",
+			b:             "x := 1 ///1g\n",
+			want:          "<<<<<<< editor\nx := 1 ///1R\n=======\nx := 1 ///1g\n>>>>>>> file\n",
+			wantConflicts: 1,
+		},
+		{
+			name: "text and colors of a line",
+			base: "foo(bar)\n",
+			a:    "foo(bar) ///4 3R\n",
+			b:    "x := foo(bar)\n",
+			want: "x := foo(bar) ///9 3R\n",
+		},
+		{
+			name: "colors and text of a line",
+			base: "foo(bar)\n",
+			a:    "x := foo(bar)\n",
+			b:    "foo(bar) ///4 3R\n",
+			want: "x := foo(bar) ///9 3R\n",
+		},
+		{
+			name:          "text and colors of the same characters",
+			base:          "foo(bar)\n",
+			a:             "foo(bar) ///4 3R\n",
+			b:             "x := foo(bar) ///9 3g\n",
+			want:          "<<<<<<< editor\nfoo(bar) ///4 3R\n=======\nx := foo(bar) ///9 3g\n>>>>>>> file\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "text of the same line",
+			base:          "a\nb\nc\n",
+			a:             "a\nb1\nc\n",
+			b:             "a\nb2\nc\n",
+			want:          "a\n<<<<<<< editor\nb1\n=======\nb2\n>>>>>>> file\nc\n",
+			wantConflicts: 1,
+		},
+		{
+			name: "inserted line before a recolored line",
+			base: "a\nbb ///1R\nc\n",
+			a:    "x\na\nbb ///1R\nc\n",
+			b:    "a\nbb ///1 1g\n",
+			want: "x\na\nbb ///1 1g\n",
+		},
+		{
+			name: "deleted and inserted lines around colored lines",
+			base: "a\nbb ///1 1R\nc\ndd\n",
+			a:    "a\nbb ///1 1R\nc\nnew\ndd ///1R\n",
+			b:    "bb ///1 1g\nc\ndd\n",
+			want: "bb ///1 1g\nc\nnew\ndd ///1R\n",
+		},
+		{
+			name: "CRLF",
+			base: "a\r\nb\r\n",
+			a:    "a ///1R\r\nb\r\n",
+			b:    "a\r\nb\r\nc\r\n",
+			want: "a ///1R\r\nb\r\nc\r\n",
+		},
+	}
+	for _, tt := range tests {
+		m, conflicts := Merge(loadText(t, tt.base), loadText(t, tt.a), loadText(t, tt.b), "editor", "file")
+		if got := writeString(t, m); got != tt.want {
+			t.Errorf("%s: merged into %q, want %q", tt.name, got, tt.want)
+		}
+		if conflicts != tt.wantConflicts {
+			t.Errorf("%s: %d conflicts, want %d", tt.name, conflicts, tt.wantConflicts)
+		}
+	}
+}
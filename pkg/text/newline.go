@@ -0,0 +1,83 @@
+package text
+
+import (
+	"github.com/patrikaleksandryan/coloride/pkg/scanner"
+)
+
+// NewLineTypeName returns the name of a new line type, i.e. "CRLF".
+func NewLineTypeName(newLineType int) string {
+	switch newLineType {
+	case scanner.LF:
+		return "LF"
+	case scanner.CRLF:
+		return "CRLF"
+	case scanner.CR:
+		return "CR"
+	default:
+		panic("impossible: newLineType")
+	}
+}
+
+// NewLineType returns the line ending of lines broken by Enter or by pasting.
+func (t *TextImpl) NewLineType() int {
+	return t.newLineType
+}
+
+// SetNewLineType sets the line ending of lines broken by Enter or by pasting. Existing lines keep theirs.
+func (t *TextImpl) SetNewLineType(newLineType int) {
+	t.newLineType = newLineType
+}
+
+// NewLineStyle returns the line ending used in the text, and whether different ones are mixed.
+// The line ending of the most lines is returned for mixed texts, of new lines for texts with a single line.
+func (t *TextImpl) NewLineStyle() (newLineType int, mixed bool) {
+	var counts [scanner.CR + 1]int
+	// The last line has no line ending
+	for l := t.first; l.next != nil; l = l.next {
+		counts[l.NewLineType]++
+	}
+	newLineType = t.newLineType
+	kinds := 0
+	for nlt, count := range counts {
+		if count != 0 {
+			kinds++
+			if count > counts[newLineType] {
+				newLineType = nlt
+			}
+		}
+	}
+	return newLineType, kinds > 1
+}
+
+// ConvertNewLines changes the line endings of the lines in the selection, or of the whole text if nothing is
+// selected, to newLineType. Converting the whole text also makes newLineType the line ending of new lines.
+func (t *TextImpl) ConvertNewLines(newLineType int) {
+	lineFrom, lineTo := 1, t.lineCount
+	if t.selected {
+		lineFrom, lineTo = t.selection.LineFrom, t.selection.LineTo
+	} else {
+		t.newLineType = newLineType
+	}
+	if lineTo == t.lineCount {
+		lineTo-- // The last line has no line ending
+	}
+	first, _ := t.LineByNum(lineFrom)
+	changed := false
+	line := first
+	for lineNum := lineFrom; lineNum <= lineTo; lineNum++ {
+		changed = changed || line.NewLineType != newLineType
+		line = line.next
+	}
+	if !changed {
+		return
+	}
+
+	t.beginEdit(editOther, lineFrom, lineTo)
+	defer t.endEdit()
+	line = first
+	for lineNum := lineFrom; lineNum <= lineTo; lineNum++ {
+		line.NewLineType = newLineType
+		line = line.next
+	}
+	t.setEdited(true)
+}
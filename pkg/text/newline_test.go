@@ -0,0 +1,85 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/patrikaleksandryan/coloride/pkg/scanner"
+)
+
+func TestNewLineStyle(t *testing.T) {
+	tests := []struct {
+		src         string
+		newLineType int // Line ending of new lines before loading
+		want        int
+		mixed       bool
+	}{
+		{"", scanner.CRLF, scanner.CRLF, false},
+		{"abc", scanner.CR, scanner.CR, false},
+		{"a\nb\n", scanner.CRLF, scanner.LF, false},
+		{"a\r\nb\r\n", scanner.LF, scanner.CRLF, false},
+		{"a\rb\r", scanner.LF, scanner.CR, false},
+		{"a\r\nb\nc\r\nd", scanner.LF, scanner.CRLF, true},
+		{"a\nb\r\nc\rd\re", scanner.LF, scanner.CR, true},
+		{"a\nb\r\n", scanner.LF, scanner.LF, true}, // Ties keep the line ending of new lines
+		{"a\nb\r\n", scanner.CRLF, scanner.CRLF, true},
+	}
+	for _, tt := range tests {
+		txt := loadText(t, tt.src)
+		txt.SetNewLineType(tt.newLineType)
+		if got, mixed := txt.NewLineStyle(); got != tt.want || mixed != tt.mixed {
+			t.Errorf("%q: style is %s, %v, want %s, %v", tt.src, NewLineTypeName(got), mixed,
+				NewLineTypeName(tt.want), tt.mixed)
+		}
+	}
+}
+
+func TestConvertNewLines(t *testing.T) {
+	tests := []struct {
+		src              string
+		selection        []int // Selected lines, nil for the whole text
+		newLineType      int
+		want             string
+		edited, newLines bool // Whether the text is changed and new lines get newLineType
+	}{
+		{"a\nb\r\nc\rd", nil, scanner.CRLF, "a\r\nb\r\nc\r\nd", true, true},
+		{"a\r\nb\r\n", nil, scanner.CRLF, "a\r\nb\r\n", false, true},
+		{"abc", nil, scanner.CR, "abc", false, true},
+		{"a\nb\nc\nd\n", []int{2, 3}, scanner.CRLF, "a\nb\r\nc\r\nd\n", true, false},
+		{"a\nb\nc", []int{2, 3}, scanner.CR, "a\nb\rc", true, false}, // The last line has no line ending
+		{"a\nb\nc", []int{3, 3}, scanner.CR, "a\nb\nc", false, false},
+		{"a\nb\r\nc", []int{2, 3}, scanner.CRLF, "a\nb\r\nc", false, false},
+	}
+	for _, tt := range tests {
+		txt := loadText(t, tt.src)
+		txt.SetNewLineType(scanner.LF)
+		if tt.selection != nil {
+			txt.SetSelection(tt.selection[0], 0, tt.selection[1], 1)
+		}
+		txt.ConvertNewLines(tt.newLineType)
+		if got := writeString(t, txt); got != tt.want {
+			t.Errorf("%q: text is %q, want %q", tt.src, got, tt.want)
+		}
+		if txt.edited != tt.edited || txt.History().CanUndo() != tt.edited {
+			t.Errorf("%q: edited is %v, can undo is %v, want %v", tt.src, txt.edited, txt.History().CanUndo(),
+				tt.edited)
+		}
+		if newLines := txt.NewLineType() == tt.newLineType; newLines != tt.newLines {
+			t.Errorf("%q: new lines end in %s", tt.src, NewLineTypeName(txt.NewLineType()))
+		}
+		if tt.edited {
+			txt.HandleUndo()
+			if got := writeString(t, txt); got != tt.src {
+				t.Errorf("%q: text is %q after undo", tt.src, got)
+			}
+		}
+	}
+
+	// Enter breaks lines with the converted line ending
+	txt := loadText(t, "ab\n")
+	txt.ConvertNewLines(scanner.CRLF)
+	txt.SetCursorX(1)
+	txt.InsertText("\n")
+	if got, want := writeString(t, txt), "a\r\nb\r\n"; got != want {
+		t.Errorf("text is %q after Enter, want %q", got, want)
+	}
+}
@@ -2,10 +2,13 @@ package text
 
 import (
 	"github.com/patrikaleksandryan/coloride/pkg/color"
+	"github.com/patrikaleksandryan/coloride/pkg/palette"
 	"github.com/patrikaleksandryan/coloride/pkg/syntax"
+	"github.com/patrikaleksandryan/coloride/pkg/theme"
 )
 
 type Reader struct {
+	view *View
 	text *TextImpl
 
 	curLine    *Line
@@ -13,30 +16,38 @@ type Reader struct {
 	column     int // 0-based character number in curLine
 
 	// Syntax highlighting
-	symbolEnd    int // Column, where current symbol (lexem, token) ends
-	symbolClass  int
-	symbolColor  color.Color // Cache of symbolClass converted to Color
-	nestingLevel int         // Can be derived from previous lines
+	lexer       syntax.Lexer
+	symbolEnd   int // Column, where current symbol (lexem, token) ends
+	symbolClass int
+	symbolColor color.Color  // Cache of symbolClass converted to Color
+	state       syntax.State // State of the lexer
+	span        int          // Index of the first span in curLine.semantic that does not start before column
+
+	matches []Match // Matches of the current search in curLine
+	match   int     // Index of the match in matches that ends after column
 }
 
 type ColoredChar struct {
 	Char    rune
 	Color   color.Color
 	BgColor color.Color
+	Found   bool // Character is a part of a match of the current search
 }
 
 // TopLine resets the internal state of the reader and returns line number of the first line visible on the screen.
 func (r *Reader) TopLine() int {
-	r.curLine = r.text.topLine
-	r.curLineNum = r.text.topLineNum
+	r.curLine, r.curLineNum = r.view.TopLine()
 	r.column = 0
 	r.symbolEnd = 0
 	r.symbolClass = 0
-	r.nestingLevel = 0
+	r.lexer = r.text.lexer
+	r.state = r.text.startState(r.curLine, r.curLineNum)
 	return r.curLineNum
 }
 
 func (r *Reader) NextLine() int {
+	// The line may have been read only partially, so the state is taken from the cache
+	r.state = r.text.endState(r.curLine, r.curLineNum)
 	r.curLine = r.curLine.next
 	if r.curLine == nil {
 		return -1
@@ -46,54 +57,68 @@ func (r *Reader) NextLine() int {
 }
 
 func SymbolClassToColor(symbolClass int) color.Color {
-	switch symbolClass {
-	case syntax.CNone:
-		return color.White
-	case syntax.CComment:
-		return color.MakeColor(120, 120, 120)
-	case syntax.CIdent:
-		return color.MakeColor(200, 200, 200)
-	case syntax.CKeyword:
-		return color.MakeColor(210, 150, 50)
-	case syntax.CString:
-		return color.MakeColor(70, 210, 50)
-	case syntax.CNumber:
-		return color.MakeColor(40, 235, 235)
-	case syntax.CProcCall:
-		return color.MakeColor(200, 180, 100)
-	default:
-		return color.White
-	}
+	return theme.SyntaxColor(symbolClass)
 }
 
 func (r *Reader) HighlightSyntax(char *ColoredChar) {
 	if r.column >= r.symbolEnd {
 		var length int
-		r.symbolClass, length, r.nestingLevel = syntax.Scan(r.curLine.chars[r.column:], r.nestingLevel, r.symbolClass)
+		r.symbolClass, length, r.state = r.lexer.Scan(r.curLine.chars, r.column, r.state)
+		if r.symbolClass == syntax.CIdent || r.symbolClass == syntax.CProcCall {
+			r.symbolClass = r.semanticClass(length)
+		}
 		r.symbolEnd = r.column + length
 		r.symbolColor = SymbolClassToColor(r.symbolClass)
 	}
 	char.Color = r.symbolColor
 }
 
+// semanticClass returns the class of the identifier of the given length at the current column found by
+// semantic analysis, or the class given by the lexer.
+func (r *Reader) semanticClass(length int) int {
+	spans := r.curLine.semantic
+	for r.span != len(spans) && spans[r.span].Column < r.column {
+		r.span++
+	}
+	if r.span != len(spans) && spans[r.span].Column == r.column && spans[r.span].Length == length {
+		return spans[r.span].Class
+	}
+	return r.symbolClass
+}
+
 func (r *Reader) Colorize(char *ColoredChar) {
 	run, _ := r.curLine.FindRun(r.column)
-	colorInfo := palette[run.color]
-	if colorInfo.overrideColor {
-		char.Color = colorInfo.Color
+	entry := palette.Get(run.color)
+	if entry.OverrideColor {
+		char.Color = entry.Color
 	}
-	if colorInfo.overrideBgColor {
-		char.BgColor = colorInfo.BgColor
+	if entry.OverrideBgColor {
+		char.BgColor = entry.BgColor
 	}
 }
 
+// HighlightMatch marks the character as found if it belongs to a match of the current search.
+func (r *Reader) HighlightMatch(char *ColoredChar) {
+	for r.match != len(r.matches) && r.matches[r.match].CharTo <= r.column {
+		r.match++
+	}
+	char.Found = r.match != len(r.matches) && r.matches[r.match].CharFrom <= r.column
+}
+
 func (r *Reader) FirstChar() (char ColoredChar, ok bool) {
 	r.column = 0 // Important to always do for ShouldPaintFullLine
+	r.matches = nil
+	r.match = 0
+	r.span = 0
+	if r.text.search != nil {
+		r.matches = r.text.search.FindInLine(r.curLine, r.curLineNum)
+	}
 	if len(r.curLine.chars) != 0 {
 		r.symbolEnd = 0
 		char.Char = r.curLine.chars[0]
 		r.HighlightSyntax(&char)
 		r.Colorize(&char)
+		r.HighlightMatch(&char)
 		ok = true
 	}
 	return
@@ -105,6 +130,7 @@ func (r *Reader) NextChar() (char ColoredChar, ok bool) {
 		char.Char = r.curLine.chars[r.column]
 		r.HighlightSyntax(&char)
 		r.Colorize(&char)
+		r.HighlightMatch(&char)
 		ok = true
 	}
 	return
@@ -116,17 +142,18 @@ func (r *Reader) ShouldPaintFullLine(bgColor *color.Color) bool {
 	run, _ := r.curLine.FindRun(r.column)
 	//fmt.Println("lineNum=", r.curLineNum, "  X=", X, "  column=", r.column, "  runNil?=", run == nil)
 	//fmt.Printf("  \"%s\"\n", string(r.curLine.chars))
-	colorInfo := palette[run.color]
-	if colorInfo.overrideBgColor {
-		*bgColor = colorInfo.BgColor
+	entry := palette.Get(run.color)
+	if entry.OverrideBgColor {
+		*bgColor = entry.BgColor
 		return true
 	}
 	return false
 }
 
-func NewReader(text *TextImpl) *Reader {
+func NewReader(view *View) *Reader {
 	r := &Reader{
-		text: text,
+		view: view,
+		text: view.text,
 	}
 	return r
 }
@@ -0,0 +1,258 @@
+package text
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+type SearchOptions struct {
+	CaseSensitive bool
+	WholeWord     bool
+	Regexp        bool // If false, the query is searched literally
+}
+
+// Match is an occurrence of a search query in a line.
+type Match struct {
+	LineNum  int
+	CharFrom int // Including
+	CharTo   int // Excluding
+
+	submatches []int // Byte offsets of regexp submatches, used to expand the replacement
+}
+
+// Search finds occurrences of a query in lines of a text.
+type Search struct {
+	re      *regexp.Regexp
+	options SearchOptions
+}
+
+func NewSearch(query string, options SearchOptions) (*Search, error) {
+	expr := query
+	if !options.Regexp {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if options.WholeWord {
+		expr = `\b(?:` + expr + `)\b`
+	}
+	if !options.CaseSensitive {
+		expr = `(?i)` + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("compile search query: %w", err)
+	}
+	return &Search{re: re, options: options}, nil
+}
+
+// FindInLine returns all non-empty matches in line l, which has number lineNum.
+func (s *Search) FindInLine(l *Line, lineNum int) []Match {
+	str := string(l.chars)
+	indices := s.re.FindAllStringSubmatchIndex(str, -1)
+	matches := make([]Match, 0, len(indices))
+	// Convert byte offsets to character positions in a single pass
+	pos, charPos := 0, 0
+	toCharPos := func(offset int) int {
+		charPos += utf8.RuneCountInString(str[pos:offset])
+		pos = offset
+		return charPos
+	}
+	for _, index := range indices {
+		if index[0] != index[1] {
+			from := toCharPos(index[0])
+			to := toCharPos(index[1])
+			matches = append(matches, Match{LineNum: lineNum, CharFrom: from, CharTo: to, submatches: index})
+		}
+	}
+	return matches
+}
+
+// expand returns the replacement for match m in line l. In regexp mode "$1" and similar are expanded.
+func (s *Search) expand(l *Line, m Match, replacement string) []rune {
+	if !s.options.Regexp {
+		return []rune(replacement)
+	}
+	return []rune(string(s.re.ExpandString(nil, replacement, string(l.chars), m.submatches)))
+}
+
+// Text
+
+// SetSearch sets the search that is used by the Find and Replace commands and whose matches are highlighted.
+// search may be nil.
+func (t *TextImpl) SetSearch(search *Search) {
+	t.search = search
+}
+
+func (t *TextImpl) Search() *Search {
+	return t.search
+}
+
+// CountMatches returns the number of matches of the current search in the whole text.
+func (t *TextImpl) CountMatches() int {
+	count := 0
+	if t.search != nil {
+		lineNum := 1
+		for line := t.first; line != nil; line = line.next {
+			count += len(t.search.FindInLine(line, lineNum))
+			lineNum++
+		}
+	}
+	return count
+}
+
+// findFrom returns the first match of the current search that starts at or after position (lineNum; x),
+// or the last match that starts before it if backward = true. The search wraps around the text,
+// so on the second visit of the starting line any match is suitable.
+func (t *TextImpl) findFrom(lineNum, x int, backward bool) (Match, bool) {
+	if t.search == nil {
+		return Match{}, false
+	}
+	line, lineNum := t.LineByNum(lineNum)
+	for i := 0; i <= t.lineCount; i++ {
+		matches := t.search.FindInLine(line, lineNum)
+		if backward {
+			for j := len(matches) - 1; j >= 0; j-- {
+				if i != 0 || matches[j].CharFrom < x {
+					return matches[j], true
+				}
+			}
+			line = line.prev
+			lineNum--
+			if line == nil {
+				line, lineNum = t.last, t.lineCount
+			}
+		} else {
+			for _, m := range matches {
+				if i != 0 || m.CharFrom >= x {
+					return m, true
+				}
+			}
+			line = line.next
+			lineNum++
+			if line == nil {
+				line, lineNum = t.first, 1
+			}
+		}
+	}
+	return Match{}, false
+}
+
+// selectMatch selects the given match and places the cursor at its end.
+func (t *TextImpl) selectMatch(m Match) {
+	line, lineNum := t.LineByNum(m.LineNum)
+	t.SetCurLine(line, lineNum)
+	t.SetCursorX(m.CharTo)
+	t.SetSelection(m.LineNum, m.CharFrom, m.LineNum, m.CharTo)
+}
+
+// selectionStart returns the position of the beginning of the selection, or of the cursor if nothing is selected.
+func (t *TextImpl) selectionStart() (lineNum, x int) {
+	if t.selected {
+		return t.selection.LineFrom, t.selection.CharFrom
+	}
+	return t.curLineNum, t.cursorX
+}
+
+// FindIncremental selects the first match at or after the beginning of the selection.
+// It is used while the query is being typed, so that the selected match grows with the query.
+func (t *TextImpl) FindIncremental() bool {
+	lineNum, x := t.selectionStart()
+	m, ok := t.findFrom(lineNum, x, false)
+	if ok {
+		t.selectMatch(m)
+	}
+	return ok
+}
+
+// FindNext selects the next match after the cursor.
+func (t *TextImpl) FindNext() bool {
+	m, ok := t.findFrom(t.curLineNum, t.cursorX, false)
+	if ok {
+		t.selectMatch(m)
+	}
+	return ok
+}
+
+// FindPrev selects the previous match before the selection or the cursor.
+func (t *TextImpl) FindPrev() bool {
+	lineNum, x := t.selectionStart()
+	m, ok := t.findFrom(lineNum, x, true)
+	if ok {
+		t.selectMatch(m)
+	}
+	return ok
+}
+
+// selectedMatch returns the match of the current search that is exactly selected, if any.
+func (t *TextImpl) selectedMatch() (Match, bool) {
+	sel := t.selection
+	if t.search != nil && t.selected && sel.LineFrom == sel.LineTo {
+		line, _ := t.LineByNum(sel.LineFrom)
+		for _, m := range t.search.FindInLine(line, sel.LineFrom) {
+			if m.CharFrom == sel.CharFrom && m.CharTo == sel.CharTo {
+				return m, true
+			}
+		}
+	}
+	return Match{}, false
+}
+
+// Replace replaces the selected match with replacement and selects the next match.
+// If no match is selected, only the next match is selected.
+func (t *TextImpl) Replace(replacement string) bool {
+	m, ok := t.selectedMatch()
+	if ok {
+		t.beginEdit(editOther, m.LineNum, m.LineNum)
+		line, lineNum := t.LineByNum(m.LineNum)
+		s := t.search.expand(line, m, replacement)
+		line.ReplaceRange(m.CharFrom, m.CharTo, s)
+		t.ClearSelection()
+		t.SetCurLine(line, lineNum)
+		t.SetCursorX(m.CharFrom + len(s))
+		t.endEdit()
+	}
+	return t.FindNext()
+}
+
+// ReplaceAll replaces all matches of the current search in the text and returns their count.
+// All replacements are undone as a single step. Only the lines from the first to the last match are changed.
+func (t *TextImpl) ReplaceAll(replacement string) int {
+	if t.search == nil {
+		return 0
+	}
+	var lineMatches [][]Match // Matches of every line, starting with the first line with a match
+	firstLineNum, lastLineNum := 0, 0
+	lineNum := 1
+	for line := t.first; line != nil; line = line.next {
+		if matches := t.search.FindInLine(line, lineNum); len(matches) != 0 {
+			if firstLineNum == 0 {
+				firstLineNum = lineNum
+			}
+			lastLineNum = lineNum
+			lineMatches = append(lineMatches, matches)
+		} else if firstLineNum != 0 {
+			lineMatches = append(lineMatches, nil)
+		}
+		lineNum++
+	}
+	if firstLineNum == 0 {
+		return 0
+	}
+
+	t.beginEdit(editOther, firstLineNum, lastLineNum)
+	count := 0
+	line, _ := t.LineByNum(firstLineNum)
+	for _, matches := range lineMatches[:lastLineNum-firstLineNum+1] {
+		// Replace from right to left to keep positions of preceding matches intact
+		for i := len(matches) - 1; i >= 0; i-- {
+			line.ReplaceRange(matches[i].CharFrom, matches[i].CharTo, t.search.expand(line, matches[i], replacement))
+		}
+		count += len(matches)
+		line = line.next
+	}
+	t.ClearSelection()
+	t.SetCursorX(t.cursorX)
+	t.setEdited(true)
+	t.endEdit()
+	return count
+}
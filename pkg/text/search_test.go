@@ -0,0 +1,210 @@
+package text
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadText returns a text loaded from s.
+func loadText(t *testing.T, s string) *TextImpl {
+	t.Helper()
+	txt := NewText().(*TextImpl)
+	err := txt.Load(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txt
+}
+
+const searchSrc = "Find the word, words and WORD.\nfoo(bar) foo(baz)\nnäher nah\n"
+
+func TestFindInLine(t *testing.T) {
+	tests := []struct {
+		query   string
+		options SearchOptions
+		want    [][3]int // Line number and character range of every match
+	}{
+		{"word", SearchOptions{}, [][3]int{{1, 9, 13}, {1, 15, 19}, {1, 25, 29}}},
+		{"word", SearchOptions{CaseSensitive: true}, [][3]int{{1, 9, 13}, {1, 15, 19}}},
+		{"word", SearchOptions{WholeWord: true}, [][3]int{{1, 9, 13}, {1, 25, 29}}},
+		{"word", SearchOptions{CaseSensitive: true, WholeWord: true}, [][3]int{{1, 9, 13}}},
+		{"foo(", SearchOptions{}, [][3]int{{2, 0, 4}, {2, 9, 13}}},
+		{`foo\((ba.)\)`, SearchOptions{Regexp: true}, [][3]int{{2, 0, 8}, {2, 9, 17}}},
+		{"na", SearchOptions{}, [][3]int{{3, 6, 8}}},
+		{"her", SearchOptions{}, [][3]int{{3, 2, 5}}}, // Positions in characters, not bytes
+		{`a|b*`, SearchOptions{Regexp: true}, [][3]int{{1, 21, 22}, {2, 4, 5}, {2, 5, 6}, {2, 13, 14}, {2, 14, 15}, {3, 7, 8}}},
+		{"missing", SearchOptions{}, nil},
+	}
+	txt := loadText(t, searchSrc)
+	for _, tt := range tests {
+		s, err := NewSearch(tt.query, tt.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got [][3]int
+		lineNum := 1
+		for l := txt.first; l != nil; l = l.next {
+			for _, m := range s.FindInLine(l, lineNum) {
+				got = append(got, [3]int{m.LineNum, m.CharFrom, m.CharTo})
+			}
+			lineNum++
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q %+v: matches are %v, want %v", tt.query, tt.options, got, tt.want)
+		}
+	}
+
+	if _, err := NewSearch("(", SearchOptions{Regexp: true}); err == nil {
+		t.Error("invalid regexp is accepted")
+	}
+	if _, err := NewSearch("(", SearchOptions{}); err != nil {
+		t.Error("literal query is taken as a regexp:", err)
+	}
+}
+
+func TestFindWrapsAround(t *testing.T) {
+	txt := loadText(t, "a x\nb\nc x\n")
+	s, err := NewSearch("x", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt.SetSearch(s)
+	line, lineNum := txt.LineByNum(3)
+	txt.SetCurLine(line, lineNum)
+	txt.SetCursorX(1)
+
+	// The cursor moves to the end of every match found
+	steps := []struct {
+		backward        bool
+		lineNum, cursor int
+	}{
+		{false, 3, 3},
+		{false, 1, 3}, // Wraps around the end
+		{false, 3, 3},
+		{true, 1, 3},
+		{true, 3, 3}, // Wraps around the beginning
+	}
+	for i, step := range steps {
+		var ok bool
+		if step.backward {
+			ok = txt.FindPrev()
+		} else {
+			ok = txt.FindNext()
+		}
+		if !ok || txt.curLineNum != step.lineNum || txt.cursorX != step.cursor {
+			t.Fatalf("step %d: found %v at %d:%d, want %d:%d", i, ok, txt.curLineNum, txt.cursorX,
+				step.lineNum, step.cursor)
+		}
+	}
+}
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		src, query  string
+		options     SearchOptions
+		replacement string
+		count       int
+		want        string
+	}{
+		{"a b a\nb\n", "a", SearchOptions{}, "xy", 2, "xy b xy\nb\n"},
+		{"foo(bar) foo(baz)\n", `foo\((\w+)\)`, SearchOptions{Regexp: true}, "$1.foo()", 2, "bar.foo() baz.foo()\n"},
+		{"foo(bar)\n", `foo(bar)`, SearchOptions{}, "$1", 1, "$1\n"}, // Expanded only in regexp mode
+		{"Go go GO\n", "go", SearchOptions{CaseSensitive: true}, "Go", 1, "Go Go GO\n"},
+		{"go gopher\n", "go", SearchOptions{WholeWord: true}, "Go", 1, "Go gopher\n"},
+		{"abc\n", "x", SearchOptions{}, "y", 0, "abc\n"},
+	}
+	for _, tt := range tests {
+		txt := loadText(t, tt.src)
+		s, err := NewSearch(tt.query, tt.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txt.SetSearch(s)
+		if count := txt.ReplaceAll(tt.replacement); count != tt.count {
+			t.Errorf("%q: %d replaced, want %d", tt.query, count, tt.count)
+		}
+		if got := writeString(t, txt); got != tt.want {
+			t.Errorf("%q: text is %q, want %q", tt.query, got, tt.want)
+		}
+
+		// Replace selects the next match first, then replaces it on the next call
+		txt = loadText(t, tt.src)
+		txt.SetSearch(s)
+		for i := 0; i <= tt.count; i++ {
+			txt.Replace(tt.replacement)
+		}
+		if got := writeString(t, txt); got != tt.want {
+			t.Errorf("%q: text is %q after Replace, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestReplaceAllWithoutMatches(t *testing.T) {
+	txt := loadText(t, "abc\ndef\n")
+	s, err := NewSearch("x", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt.SetSearch(s)
+	edits := txt.Edits()
+	if txt.ReplaceAll("y") != 0 {
+		t.Fatal("matches are replaced")
+	}
+	if txt.Edits() != edits || txt.History().CanUndo() || txt.edited {
+		t.Error("a replacement without matches is an edit")
+	}
+}
+
+func TestReplaceAllUndo(t *testing.T) {
+	const src = "a\nb\nxa\nc\na\n"
+	txt := loadText(t, src)
+	s, err := NewSearch("a", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt.SetSearch(s)
+	if txt.ReplaceAll("bb") != 3 {
+		t.Fatal("wrong number of replacements")
+	}
+	txt.HandleUndo()
+	if got := writeString(t, txt); got != src {
+		t.Errorf("text after undo is %q, want %q", got, src)
+	}
+	if txt.History().CanUndo() {
+		t.Error("replacements are undone in more than one step")
+	}
+}
+
+func TestReplaceKeepsColors(t *testing.T) {
+	tests := []struct {
+		src, query, replacement string
+		want                    string
+	}{
+		// The replacement gets the color of the first replaced character
+		{"ab cd ef ///3 2R\n", "cd", "wxyz", "ab wxyz ef ///3 4R\n"},
+		{"ab cd ef ///3 2R\n", "cd", "", "ab  ef\n"},
+		{"ab cd ef ///3 2R\n", "c", "xyz", "ab xyzd ef ///3 4R\n"},
+		{"ab cd ef ///3 2R\n", "b c", "-", "a-d ef ///2 1R\n"},
+		{"ab cd ef ///3 2R\n", "ab", "x", "x cd ef ///2 2R\n"},
+		{"ab cd ef ///3 2R 1 2g\n", "d e", "_", "ab c_f ///3 2R 1g\n"},
+		{"ab ///1R\n", "b", "xyz", "axyz ///1R\n"},
+	}
+	for _, tt := range tests {
+		txt := loadText(t, tt.src)
+		s, err := NewSearch(tt.query, SearchOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		txt.SetSearch(s)
+		txt.ReplaceAll(tt.replacement)
+		if got := writeString(t, txt); got != tt.want {
+			t.Errorf("%q in %q: text is %q, want %q", tt.query, tt.src, got, tt.want)
+		}
+		l := txt.first
+		if colors := l.CharColors(); len(colors) != len(l.chars)+1 {
+			t.Errorf("%q in %q: runs have %d characters, the line has %d", tt.query, tt.src, len(colors),
+				len(l.chars)+1)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package text
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"time"
+)
+
+// FileStamp identifies the contents of a file, so that changes made by other programs can be noticed.
+type FileStamp struct {
+	ModTime time.Time
+	Size    int64
+	Hash    [sha256.Size]byte
+}
+
+// setFile remembers the contents of the file fname that has just been loaded or saved.
+func (t *TextImpl) setFile(fname string, data []byte) {
+	t.fileData = data
+	t.fileStamp = FileStamp{Size: int64(len(data)), Hash: sha256.Sum256(data)}
+	if info, err := os.Stat(fname); err == nil {
+		t.fileStamp.ModTime = info.ModTime()
+	}
+}
+
+// FileData returns the contents of the file as the text was loaded from it or saved to it last, nil if the text
+// has not been loaded from a file.
+func (t *TextImpl) FileData() []byte {
+	return t.fileData
+}
+
+// FileChanged reports whether the file fname has been changed since the text was loaded from it or saved to it.
+// The contents are only compared if the time or the size of the file differ, a file that has only been
+// touched is not changed.
+func (t *TextImpl) FileChanged(fname string) (bool, error) {
+	info, err := os.Stat(fname)
+	if err != nil {
+		return false, fmt.Errorf("stat file: %w", err)
+	}
+	if info.ModTime().Equal(t.fileStamp.ModTime) && info.Size() == t.fileStamp.Size {
+		return false, nil
+	}
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return false, fmt.Errorf("read file: %w", err)
+	}
+	if sha256.Sum256(data) != t.fileStamp.Hash {
+		return true, nil
+	}
+	t.fileStamp.ModTime = info.ModTime()
+	return false, nil
+}
+
+// AcceptFile makes the current contents of the file fname the ones the text is compared with, i.e. when the
+// user keeps the text although the file has been changed.
+func (t *TextImpl) AcceptFile(fname string) error {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+	t.setFile(fname, data)
+	return nil
+}
+
+// SetContents replaces all lines of the text with copies of the lines of other, as one edit that can be undone.
+func (t *TextImpl) SetContents(other *TextImpl) {
+	var lines []*Line
+	for l := other.first; l != nil; l = l.next {
+		lines = append(lines, l)
+	}
+	lineNum := t.curLineNum
+	t.beginEdit(editOther, 1, t.lineCount)
+	defer t.endEdit()
+	t.ClearSelection()
+	t.replaceLines(1, t.lineCount, lines)
+	line, lineNum := t.LineByNum(lineNum)
+	t.SetCurLine(line, lineNum)
+	t.setEdited(true)
+}
+
+// textFromData returns a text loaded from data with the color comments of t, i.e. an earlier revision of t.
+func (t *TextImpl) textFromData(data []byte) (*TextImpl, error) {
+	other := NewText().(*TextImpl)
+	other.colorComment = t.colorComment
+	err := other.Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return other, nil
+}
+
+// MergeFile merges the changes made to the file fname by another program into the text, as one edit that can
+// be undone. Returns the number of conflicts, which are marked in the text.
+func (t *TextImpl) MergeFile(fname string) (int, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return 0, fmt.Errorf("read file: %w", err)
+	}
+	base, err := t.textFromData(t.fileData)
+	if err != nil {
+		return 0, err
+	}
+	changed, err := t.textFromData(data)
+	if err != nil {
+		return 0, err
+	}
+	merged, conflicts := Merge(base, t, changed, "editor", "file")
+	t.SetContents(merged)
+	t.setFile(fname, data)
+	return conflicts, nil
+}
@@ -0,0 +1,146 @@
+package text
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// loadFile writes data to a new file and returns the file name and a text loaded from it.
+func loadFile(t *testing.T, data string) (string, *TextImpl) {
+	t.Helper()
+	fname := filepath.Join(t.TempDir(), "a.go")
+	err := os.WriteFile(fname, []byte(data), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt := NewText().(*TextImpl)
+	err = txt.LoadFromFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fname, txt
+}
+
+// changeFile replaces the contents of the file fname and sets its modification time to the next hour.
+func changeFile(t *testing.T, fname, data string) {
+	t.Helper()
+	err := os.WriteFile(fname, []byte(data), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := time.Now().Add(time.Hour)
+	err = os.Chtimes(fname, next, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileChanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string // New contents of the file
+		changed bool
+	}{
+		{"touched", "abc\ndef\n", false},
+		{"same size", "abc\nxyz\n", true},
+		{"other size", "abc\n", true},
+	}
+	for _, tt := range tests {
+		fname, txt := loadFile(t, "abc\ndef\n")
+		changeFile(t, fname, tt.data)
+		changed, err := txt.FileChanged(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != tt.changed {
+			t.Errorf("%s: changed is %v, want %v", tt.name, changed, tt.changed)
+		}
+		if changed {
+			continue
+		}
+		// The new time is remembered, so that the contents are not read again
+		err = os.WriteFile(fname, []byte("abc\nxyz\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chtimes(fname, info.ModTime(), txt.fileStamp.ModTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed, _ := txt.FileChanged(fname); changed {
+			t.Errorf("%s: the time of the file is not remembered", tt.name)
+		}
+	}
+
+	fname, txt := loadFile(t, "abc\n")
+	os.Remove(fname)
+	if _, err := txt.FileChanged(fname); err == nil {
+		t.Error("a deleted file is not an error")
+	}
+}
+
+func TestMergeFile(t *testing.T) {
+	tests := []struct {
+		name              string
+		file, edit, other string // Contents of the file, as edited in the text and as changed by another program
+		want              string
+		conflicts         int
+	}{
+		{"apart", "a\nb\nc\nd\n", "a\nB\nc\nd\n", "a\nb\nc\nD\n", "a\nB\nc\nD\n", 0},
+		{"colors", "a\nb\nc\n", "a ///1R\nb\nc\n", "a\nb\nc ///1g\n", "a ///1R\nb\nc ///1g\n", 0},
+		{"same change", "a\nb\n", "a\nx\n", "a\nx\n", "a\nx\n", 0},
+		{"conflict", "a\nb\nc\n", "a\nx\nc\n", "a\ny\nc\n", "a\n<<<<<<< editor\nx\n=======\ny\n>>>>>>> file\nc\n", 1},
+	}
+	for _, tt := range tests {
+		fname, txt := loadFile(t, tt.file)
+		txt.SetContents(loadText(t, tt.edit))
+		changeFile(t, fname, tt.other)
+		conflicts, err := txt.MergeFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := writeString(t, txt); got != tt.want || conflicts != tt.conflicts {
+			t.Errorf("%s: text is %q with %d conflicts, want %q with %d", tt.name, got, conflicts, tt.want, tt.conflicts)
+		}
+		if changed, _ := txt.FileChanged(fname); changed {
+			t.Errorf("%s: the file is changed after merging", tt.name)
+		}
+
+		// The merge is one edit
+		if tt.want == tt.edit {
+			continue
+		}
+		txt.HandleUndo()
+		if got := writeString(t, txt); got != tt.edit {
+			t.Errorf("%s: text is %q after undo, want %q", tt.name, got, tt.edit)
+		}
+	}
+}
+
+func TestMergeFileWithoutData(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "a.go")
+	err := os.WriteFile(fname, []byte("a\nb\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the contents the text was loaded from, the whole text conflicts with the file
+	txt := loadText(t, "a\nx\n")
+	conflicts, err := txt.MergeFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "<<<<<<< editor\na\nx\n=======\na\nb\n>>>>>>> file\n"
+	if got := writeString(t, txt); got != want || conflicts != 1 {
+		t.Errorf("text is %q with %d conflicts, want %q with 1", got, conflicts, want)
+	}
+	if txt.FileData() == nil {
+		t.Error("the contents of the file are not remembered")
+	}
+}
@@ -2,14 +2,24 @@ package text
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
+	"unicode"
 
+	"github.com/patrikaleksandryan/coloride/pkg/atomicfile"
+	"github.com/patrikaleksandryan/coloride/pkg/charset"
 	"github.com/patrikaleksandryan/coloride/pkg/colorcode"
 	"github.com/patrikaleksandryan/coloride/pkg/scanner"
-	"github.com/veandco/go-sdl2/sdl"
+	"github.com/patrikaleksandryan/coloride/pkg/syntax"
+	"github.com/patrikaleksandryan/coloride/pkg/theme"
+)
+
+const (
+	defaultPageLines = 25 // Lines in a page if the text has no view
 )
 
 type Text interface {
@@ -30,29 +40,32 @@ type Text interface {
 	HandleCopy()
 	HandlePaste()
 	HandleSelectAll()
+	HandleUndo()
+	HandleRedo()
+
+	SetView(view *View)
+	RemoveView(view *View)
+	View() *View
+	SetClipboard(clipboard Clipboard)
 
-	Reader() *Reader
 	CurLine() *Line
 	CurLineNum() int
 	LineByNum(lineNum int) (line *Line, correctedNum int)
+	LineNum(l *Line) int
 	SetCurLine(line *Line, lineNum int)
-	TopLine() (*Line, int)
 	CursorX() int
 	SetCursorX(cursorX int)
 
 	InSelection(lineNum, charNum int) bool
 	ClearSelection()
+	HasSelection() bool
 	SetSelection(lineFrom, charFrom, lineTo, charTo int)
 	StartMouseSelection()
 	ContinueMouseSelection()
 
-	Resize(w, h int)
 	SetUpdaters(editedUpdater EditedUpdater, posUpdater PosUpdater)
-	SetFontSize(charW, charH int)
 	SetTabSize(tabSize int)
 	TabSize() int
-	ScrollValues() (scrollX, scrollY int)
-	ScrollDelta(dy int)
 
 	VisualToCursorX(l *Line, x int) int
 	CursorXToVisual(l *Line, x int) int
@@ -61,9 +74,40 @@ type Text interface {
 	DeleteLine(l *Line)
 
 	Clear()
+	Load(r io.Reader) error
+	SetEncoding(e charset.Encoding)
+	Encoding() charset.Encoding
+	SetNewLineType(newLineType int)
+	NewLineType() int
+	NewLineStyle() (newLineType int, mixed bool)
+	ConvertNewLines(newLineType int)
+	Edits() int
+	WriteJournal(w io.Writer) error
+	ReadJournal(r io.Reader) error
+	FileChanged(fname string) (bool, error)
+	AcceptFile(fname string) error
+	MergeFile(fname string) (int, error)
 	LoadFromFile(fname string) error
 	SaveToFile(fname string) error
+	SetLexer(lexer syntax.Lexer)
+	Lexer() syntax.Lexer
+	Version() int
+	SetSemantic(version int, spans []syntax.Span) bool
+	ClearSemantic()
+	WritePlain(w io.Writer) error
+	WriteUTF8(w io.Writer) error
 	ColorizeSelection(color int)
+
+	SetSearch(search *Search)
+	Search() *Search
+	CountMatches() int
+	FindIncremental() bool
+	FindNext() bool
+	FindPrev() bool
+	FindColorRun(clr int, backward bool) bool
+	CursorColor() int
+	Replace(replacement string) bool
+	ReplaceAll(replacement string) int
 }
 
 type EditedUpdater interface {
@@ -95,17 +139,25 @@ type TextImpl struct {
 	oldCursorX    int   // Value of cursorX, saved in SelectionBefore
 
 	first, last *Line // First and last line of document
-	topLine     *Line // First line visible on the screen
-	topLineNum  int   // 1-based
+	index       lineIndex
 	lineCount   int
-
-	w, h             int // Size of editor frame in pixels
-	charW, charH     int // Size of character in pixels
-	scrollX, scrollY int // Text scroll relative to frame in pixels, positive
-	tabSize          int // Number of spaces in a tab
-
-	reader        *Reader
-	edited        bool // If file was edited after it was opened
+	tabSize     int // Number of spaces in a tab
+
+	views         []*View
+	view          *View // Active view that follows the cursor, nil if the text is not displayed
+	clipboard     Clipboard
+	history       History
+	search        *Search // Current search, its matches are highlighted
+	lexer         syntax.Lexer
+	validStates   int                 // Number of first lines with up to date Line.endState
+	version       int                 // Incremented on every change of characters
+	edits         int                 // Incremented on every edit, see Edits
+	colorComment  syntax.ColorComment // How color codes are written in the file
+	encoding      charset.Encoding    // Encoding of the file
+	newLineType   int                 // Line ending of new lines, one of New Line Type constants in scanner.go
+	fileData      []byte              // Contents of the file when it was loaded or saved last, see FileData
+	fileStamp     FileStamp           // Stamp of fileData, see FileChanged
+	edited        bool                // If file was edited after it was opened
 	editedUpdater EditedUpdater
 	posUpdater    PosUpdater
 }
@@ -118,24 +170,37 @@ func (s Selection) isInverted() bool {
 	return s.LineFrom > s.LineTo || s.LineFrom == s.LineTo && s.CharFrom > s.CharTo
 }
 
-func NewText(w, h, charW, charH int) Text {
+// NewText creates an empty text. To be displayed, it needs a view (see NewView).
+func NewText() Text {
 	line := NewLine()
 	text := &TextImpl{
-		first:      line,
-		last:       line,
-		curLine:    line,
-		curLineNum: 1,
-		topLine:    line,
-		topLineNum: 1,
-		lineCount:  1,
-	}
-	text.Resize(w, h)
-	text.SetFontSize(charW, charH)
+		first:        line,
+		last:         line,
+		curLine:      line,
+		curLineNum:   1,
+		lineCount:    1,
+		clipboard:    &MemoryClipboard{},
+		lexer:        syntax.Default(),
+		colorComment: syntax.DefaultColorComment,
+	}
+	text.index.Insert(1, line)
 	text.SetTabSize(4)
-	text.reader = NewReader(text)
 	return text
 }
 
+// SetView makes the given view active. It must be one of the views of the text.
+func (t *TextImpl) SetView(view *View) {
+	t.view = view
+}
+
+func (t *TextImpl) View() *View {
+	return t.view
+}
+
+func (t *TextImpl) SetClipboard(clipboard Clipboard) {
+	t.clipboard = clipboard
+}
+
 func (t *TextImpl) SetUpdaters(editedUpdater EditedUpdater, posUpdater PosUpdater) {
 	t.editedUpdater = editedUpdater
 	t.posUpdater = posUpdater
@@ -160,36 +225,102 @@ func (t *TextImpl) Clear() {
 	t.cursorX = 0
 	t.cursorMem = 0
 	t.curLineNum = 1
-	t.topLineNum = 1
 	t.selected = false
 	t.oldCurLine = nil
 	t.oldCurLineNum = 1
 	t.oldCursorX = 0
-	t.scrollX = 0
-	t.scrollY = 0
 	t.first = NewLine()
 	t.last = t.first
-	t.topLine = t.first
 	t.curLine = t.first
 	t.lineCount = 1
+	t.index.Reset(t.first)
+	t.validStates = 0
+	t.version++
+	t.edits++
+	for _, v := range t.views {
+		v.ScrollTo(0, 0)
+	}
+	t.history.Clear()
 	t.setEdited(false)
 }
 
 func (t *TextImpl) LoadFromFile(fname string) error {
-	t.Clear()
-	f, err := os.Open(fname)
+	data, err := os.ReadFile(fname)
 	if err != nil {
 		return fmt.Errorf("open file: %w", err)
 	}
-	defer f.Close()
-
-	buf := bufio.NewReader(f)
-	s := scanner.NewScanner(buf)
 
-	err = t.load(s)
+	t.encoding = charset.Detect(data)
+	decoded := charset.Decode(data, t.encoding)
+	first := firstLine(decoded)
+	t.lexer = syntax.ForFile(fname, first)
+	t.colorComment = syntax.ColorCommentFor(fname, first)
+	err = t.loadFrom(bufio.NewReader(bytes.NewReader(decoded)))
 	if err != nil {
 		return fmt.Errorf("load file: %w", err)
 	}
+	t.setFile(fname, data)
+	return nil
+}
+
+// firstLine returns the beginning of the first line of data.
+func firstLine(data []byte) string {
+	if len(data) > 256 {
+		data = data[:256]
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSuffix(line, "\r")
+}
+
+// SetLexer sets the lexer that is used for syntax highlighting.
+func (t *TextImpl) SetLexer(lexer syntax.Lexer) {
+	t.lexer = lexer
+	t.invalidateStates(1)
+}
+
+func (t *TextImpl) Lexer() syntax.Lexer {
+	return t.lexer
+}
+
+// SetColorComment sets the comment syntax of color codes that is used by Load and Write.
+func (t *TextImpl) SetColorComment(c syntax.ColorComment) {
+	t.colorComment = c
+}
+
+func (t *TextImpl) ColorComment() syntax.ColorComment {
+	return t.colorComment
+}
+
+// Load replaces the contents of the text with the data read from r, detecting its encoding.
+func (t *TextImpl) Load(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	t.encoding = charset.Detect(data)
+	return t.loadFrom(bufio.NewReader(bytes.NewReader(charset.Decode(data, t.encoding))))
+}
+
+// SetEncoding sets the encoding in which the text is written.
+func (t *TextImpl) SetEncoding(e charset.Encoding) {
+	t.encoding = e
+}
+
+// Encoding returns the encoding of the loaded file, UTF-8 for new texts.
+func (t *TextImpl) Encoding() charset.Encoding {
+	return t.encoding
+}
+
+func (t *TextImpl) loadFrom(buf *bufio.Reader) error {
+	t.Clear()
+	s := scanner.NewScanner(buf, t.colorComment.Open)
+
+	t.history.disabled = true
+	err := t.load(s)
+	t.history.disabled = false
+	if err != nil {
+		return err
+	}
 	t.setEdited(false)
 	return nil
 }
@@ -204,18 +335,27 @@ func (t *TextImpl) load(s *scanner.Scanner) error {
 		}
 
 		if s.Sym == scanner.ColorMarker {
-			toAppend, t.curLine.spaces = splitTrailingWhitespace(toAppend)
 			s.Scan()
 			code := make([]rune, 0, 20)
 			for s.Sym != scanner.EOT && s.Sym != scanner.NewLine {
 				if s.Sym == scanner.String {
 					code = append(code, s.String...)
+				} else if t.colorComment.Close != "" {
+					// Only the last comment of the line can be a color comment, the ones before it are a part of the text
+					toAppend = append(append(toAppend, []rune(t.colorComment.Open)...), code...)
+					code = code[:0]
 				} else /* s.Sym == scanner.ColorMarker */ {
-					code = append(code, '/', '/', '/')
+					code = append(code, []rune(t.colorComment.Open)...)
 				}
 				s.Scan()
 			}
-			t.curLine.colorCode = code
+			if colorCode, ok := t.trimColorComment(code); ok {
+				toAppend, t.curLine.spaces = splitTrailingWhitespace(toAppend)
+				t.curLine.colorCode = colorCode
+			} else {
+				// The comment is closed before the end of the line, so it is a part of the text
+				toAppend = append(append(toAppend, []rune(t.colorComment.Open)...), code...)
+			}
 		}
 
 		for _, r := range toAppend {
@@ -226,13 +366,14 @@ func (t *TextImpl) load(s *scanner.Scanner) error {
 		line := t.curLine
 
 		if s.Sym == scanner.NewLine {
-			t.curLine.NewLineType = s.NewLineType
 			t.HandleEnter()
+			line.NewLineType = s.NewLineType
 			s.Scan()
 		}
 
 		line.ApplyColorCode()
 	}
+	t.newLineType, _ = t.NewLineStyle()
 	t.MoveToBeginning()
 	return nil
 }
@@ -247,36 +388,95 @@ func splitTrailingWhitespace(s []rune) ([]rune, []rune) {
 }
 
 func (t *TextImpl) SaveToFile(fname string) error {
-	f, err := os.Create(fname)
+	// The file is written with the color comments of its language, the old ones are kept if saving fails
+	colorComment := t.colorComment
+	t.colorComment = syntax.ColorCommentFor(fname, string(t.first.chars))
+	err := t.saveToFile(fname)
 	if err != nil {
-		return fmt.Errorf("open file: %w", err)
+		t.colorComment = colorComment
+		return err
 	}
-	buf := bufio.NewWriter(f)
+	t.SetLexer(syntax.ForFile(fname, string(t.first.chars)))
+	t.history.markSaved()
+	t.setEdited(false)
+	return nil
+}
 
-	err = t.write(buf)
+// saveToFile replaces the file atomically, so that it is left unchanged if saving fails.
+func (t *TextImpl) saveToFile(fname string) error {
+	var buf bytes.Buffer
+	err := t.Write(&buf)
 	if err != nil {
-		f.Close()
 		return fmt.Errorf("write file: %w", err)
 	}
+	data := buf.Bytes()
+	err = atomicfile.WriteFile(fname, theme.Backup, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	t.setFile(fname, data)
+	return nil
+}
+
+// Write writes the text to w together with the color comments.
+func (t *TextImpl) Write(w io.Writer) error {
+	return t.writeTo(w, true)
+}
+
+// WritePlain writes the text to w without the color comments and the whitespace before them.
+func (t *TextImpl) WritePlain(w io.Writer) error {
+	return t.writeTo(w, false)
+}
+
+// WriteUTF8 writes the text to w like WritePlain, but in UTF-8 whatever the encoding of the file,
+// so that the code can be analyzed.
+func (t *TextImpl) WriteUTF8(w io.Writer) error {
+	return t.writeUTF8(w, false)
+}
+
+func (t *TextImpl) writeTo(w io.Writer, withColors bool) error {
+	if t.encoding != charset.UTF8 {
+		return t.writeEncoded(w, withColors)
+	}
+	return t.writeUTF8(w, withColors)
+}
 
+func (t *TextImpl) writeUTF8(w io.Writer, withColors bool) error {
+	buf := bufio.NewWriter(w)
+	err := t.write(buf, withColors)
+	if err != nil {
+		return err
+	}
 	err = buf.Flush()
 	if err != nil {
-		f.Close()
 		return fmt.Errorf("flush buffer: %w", err)
 	}
+	return nil
+}
 
-	err = f.Close()
+// writeEncoded writes the text to w in its encoding, which is done in memory.
+func (t *TextImpl) writeEncoded(w io.Writer, withColors bool) error {
+	var b bytes.Buffer
+	buf := bufio.NewWriter(&b)
+	err := t.write(buf, withColors)
 	if err != nil {
-		return fmt.Errorf("close file: %w", err)
+		return err
 	}
-	t.setEdited(false)
-	return nil
+	err = buf.Flush()
+	if err != nil {
+		return fmt.Errorf("flush buffer: %w", err)
+	}
+	_, err = w.Write(charset.Encode(b.Bytes(), t.encoding))
+	return err
 }
 
-func (t *TextImpl) write(buf *bufio.Writer) error {
+func (t *TextImpl) write(buf *bufio.Writer, withColors bool) error {
 	line := t.first
 	for line != nil {
-		err := t.writeLine(buf, line)
+		err := t.writeLine(buf, line, withColors)
 		if err != nil {
 			return err
 		}
@@ -285,15 +485,15 @@ func (t *TextImpl) write(buf *bufio.Writer) error {
 	return nil
 }
 
-func (t *TextImpl) writeLine(buf *bufio.Writer, line *Line) error {
-	_, err := buf.WriteString(string(line.chars))
+func (t *TextImpl) writeLine(buf *bufio.Writer, line *Line, withColors bool) error {
+	err := writeChars(buf, line.chars)
 	if err != nil {
 		return err
 	}
 
-	if line.IsColorized() {
+	if withColors && line.IsColorized() {
 		if len(line.spaces) != 0 {
-			_, err = buf.WriteString(string(line.spaces))
+			err = writeChars(buf, line.spaces)
 		} else {
 			_, err = buf.WriteString("\t\t")
 		}
@@ -305,17 +505,54 @@ func (t *TextImpl) writeLine(buf *bufio.Writer, line *Line) error {
 		t.closeColorComment(buf)
 	}
 
-	err = t.writeNewLine(buf, line.NewLineType)
+	// The last line is not terminated, a file ending with a new line has an empty last line
+	if line.next != nil {
+		err = t.writeNewLine(buf, line.NewLineType)
+	}
 	return err
 }
 
+// writeChars writes the characters in UTF-8. The bytes of invalid UTF-8 that were read are written unchanged.
+func writeChars(buf *bufio.Writer, chars []rune) error {
+	for _, r := range chars {
+		var err error
+		if b, ok := charset.RawByteOf(r); ok {
+			err = buf.WriteByte(b)
+		} else {
+			_, err = buf.WriteRune(r)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t *TextImpl) openColorComment(buf *bufio.Writer) error {
-	_, err := buf.WriteString("///")
+	_, err := buf.WriteString(t.colorComment.Open)
+	if err == nil && t.colorComment.Close != "" {
+		err = buf.WriteByte(' ')
+	}
 	return err
 }
 
-func (t *TextImpl) closeColorComment(_ *bufio.Writer) error {
-	return nil
+func (t *TextImpl) closeColorComment(buf *bufio.Writer) error {
+	if t.colorComment.Close == "" {
+		return nil
+	}
+	_, err := buf.WriteString(" " + t.colorComment.Close)
+	return err
+}
+
+// trimColorComment removes the end of the color comment from code, if the comment has one.
+// Reports false if the comment does not end the line, so that it is not a color comment.
+func (t *TextImpl) trimColorComment(code []rune) ([]rune, bool) {
+	if t.colorComment.Close == "" {
+		return code, true
+	}
+	s := strings.TrimRightFunc(string(code), unicode.IsSpace)
+	s, ok := strings.CutSuffix(s, t.colorComment.Close)
+	return []rune(s), ok
 }
 
 func (t *TextImpl) writeColorCode(buf *bufio.Writer, runs *Run) error {
@@ -343,17 +580,17 @@ func (t *TextImpl) writeColorCode(buf *bufio.Writer, runs *Run) error {
 			if err != nil {
 				return err
 			}
-		} else if run.next == nil { // Letter, because it is the last one
-			_, err := buf.WriteRune(colorcode.ToLetter(run.color))
+		} else if run.next == nil { // Letter or name, because it is the last one
+			_, err := buf.WriteString(colorcode.Code(run.color))
 			if err != nil {
 				return err
 			}
-		} else { // Number + Letter
+		} else { // Number + Letter or name
 			_, err := buf.WriteString(strconv.Itoa(run.length))
 			if err != nil {
 				return err
 			}
-			_, err = buf.WriteRune(colorcode.ToLetter(run.color))
+			_, err = buf.WriteString(colorcode.Code(run.color))
 			if err != nil {
 				return err
 			}
@@ -381,10 +618,6 @@ func (t *TextImpl) writeNewLine(buf *bufio.Writer, newLineType int) error {
 	return err
 }
 
-func (t *TextImpl) Reader() *Reader {
-	return t.reader
-}
-
 func (t *TextImpl) UpdateCursorMem() {
 	t.cursorMem = t.CursorXToVisual(t.curLine, t.cursorX)
 }
@@ -397,6 +630,8 @@ func (t *TextImpl) HandleDelete() {
 	if t.selected {
 		t.DeleteSelectedText()
 	} else {
+		t.beginEdit(editOther, t.curLineNum, t.curLineNum+1)
+		defer t.endEdit()
 		t.ClearSelection()
 		if t.cursorX != len(t.curLine.chars) {
 			t.curLine.DeleteChar(t.cursorX)
@@ -416,6 +651,8 @@ func (t *TextImpl) HandleBackspace() {
 	if t.selected {
 		t.DeleteSelectedText()
 	} else {
+		t.beginEdit(editOther, t.curLineNum-1, t.curLineNum)
+		defer t.endEdit()
 		t.ClearSelection()
 		if t.cursorX != 0 {
 			t.curLine.DeleteChar(t.cursorX - 1)
@@ -475,12 +712,19 @@ func (t *TextImpl) IndentLength(line *Line) int {
 	return i
 }
 
+// HasSelection reports whether some text is selected.
+func (t *TextImpl) HasSelection() bool {
+	return t.selected
+}
+
 func (t *TextImpl) ClearSelection() {
 	t.selected = false
 }
 
 func (t *TextImpl) DeleteSelectedText() {
 	if t.selected {
+		t.beginEdit(editOther, t.selection.LineFrom, t.selection.LineTo)
+		defer t.endEdit()
 		sel := t.selection
 		// First line of selection
 		line, lineNum := t.LineByNum(t.selection.LineFrom)
@@ -558,33 +802,32 @@ func (t *TextImpl) SelectionAfter(shift bool) {
 	t.UpdatePos()
 }
 
-func (t *TextImpl) CursorTooLow() bool {
-	return t.curLineNum*t.charH > t.scrollY+t.h
+// MoveToCursor scrolls the active view, so that the cursor is visible.
+func (t *TextImpl) MoveToCursor() {
+	if t.view != nil {
+		t.view.MoveToCursor()
+	}
 }
 
-func (t *TextImpl) CursorTooHigh() bool {
-	return (t.curLineNum-1)*t.charH < t.scrollY
+// clampScroll keeps the scroll position of all views within the text, which may have become shorter.
+func (t *TextImpl) clampScroll() {
+	for _, v := range t.views {
+		v.ScrollDelta(0)
+	}
 }
 
-func (t *TextImpl) MoveToCursor() {
-	if t.CursorTooLow() {
-		y := t.curLineNum * t.charH
-		t.scrollY = y - t.h
-	} else if t.CursorTooHigh() {
-		y := (t.curLineNum - 1) * t.charH
-		t.scrollY = y
+// pageLines returns the number of lines moved by Page Up and Page Down.
+func (t *TextImpl) pageLines() int {
+	if t.view == nil {
+		return defaultPageLines
 	}
+	return t.view.PageLines()
 }
 
-func (t *TextImpl) ScrollDelta(dy int) {
-	t.scrollY += dy
-
-	max := t.lineCount*t.charH - t.h
-	if t.scrollY > max {
-		t.scrollY = max
-	}
-	if t.scrollY < 0 {
-		t.scrollY = 0
+// scrollLines scrolls the active view by the given number of lines.
+func (t *TextImpl) scrollLines(lines int) {
+	if t.view != nil {
+		t.view.ScrollDelta(lines * t.view.charH)
 	}
 }
 
@@ -608,6 +851,8 @@ func (t *TextImpl) ContinueMouseSelection() {
 }
 
 func (t *TextImpl) HandleEnter() {
+	t.beginEdit(editOther, t.curLineNum, t.curLineNum)
+	defer t.endEdit()
 	t.ClearSelection()
 
 	t.SplitLine(t.curLine, t.cursorX)
@@ -746,22 +991,14 @@ func (t *TextImpl) CursorXToVisual(l *Line, x int) int {
 func (t *TextImpl) HandlePageUp(shift bool) {
 	t.SelectionBefore()
 
-	lines := t.h / t.charH
-	if lines == 0 {
-		lines = 1
-	}
-
+	lines := t.pageLines()
 	for t.curLine.prev != nil && lines != 0 {
 		t.curLineNum--
 		t.curLine = t.curLine.prev
-		t.scrollY -= t.charH
+		t.scrollLines(-1)
 		lines--
 	}
 
-	if t.scrollY < 0 {
-		t.scrollY = 0
-	}
-
 	if lines == 0 {
 		t.cursorX = t.VisualToCursorX(t.curLine, t.cursorMem)
 		if t.cursorX > len(t.curLine.chars) {
@@ -777,15 +1014,11 @@ func (t *TextImpl) HandlePageUp(shift bool) {
 func (t *TextImpl) HandlePageDown(shift bool) {
 	t.SelectionBefore()
 
-	lines := t.h / t.charH
-	if lines == 0 {
-		lines = 1
-	}
-
+	lines := t.pageLines()
 	for t.curLine.next != nil && lines != 0 {
 		t.curLineNum++
 		t.curLine = t.curLine.next
-		t.scrollY += t.charH
+		t.scrollLines(1)
 		lines--
 	}
 
@@ -802,6 +1035,8 @@ func (t *TextImpl) HandlePageDown(shift bool) {
 }
 
 func (t *TextImpl) HandleChar(r rune) {
+	t.beginTyping(r)
+	defer t.endEdit()
 	t.SelectionBefore()
 
 	t.DeleteSelectedText()
@@ -820,12 +1055,15 @@ func (t *TextImpl) HandleCut() {
 
 func (t *TextImpl) HandleCopy() {
 	text := t.SelectedText()
-	sdl.SetClipboardText(text)
+	t.clipboard.SetText(text)
 }
 
 func (t *TextImpl) HandlePaste() {
-	text, err := sdl.GetClipboardText()
+	text, err := t.clipboard.Text()
 	if err == nil {
+		lineFrom, lineTo := t.editRange()
+		t.beginEdit(editOther, lineFrom, lineTo)
+		defer t.endEdit()
 		t.DeleteSelectedText()
 		t.InsertText(text)
 	}
@@ -863,6 +1101,9 @@ func (t *TextImpl) SelectedText() string {
 }
 
 func (t *TextImpl) InsertText(text string) {
+	lineFrom, lineTo := t.editRange()
+	t.beginEdit(editOther, lineFrom, lineTo)
+	defer t.endEdit()
 	for _, ch := range text {
 		if ch == '\n' {
 			t.HandleEnter()
@@ -885,15 +1126,17 @@ func (t *TextImpl) CurLineNum() int {
 // It returns the first line if lineNum <= 0.
 // It returns the last line if lineNum is bigger than the last line.
 func (t *TextImpl) LineByNum(lineNum int) (*Line, int) {
-	l := t.first
-	correctedNum := 1
-	if lineNum > 0 {
-		for l.next != nil && correctedNum != lineNum {
-			l = l.next
-			correctedNum++
-		}
+	if lineNum < 1 {
+		lineNum = 1
+	} else if lineNum > t.lineCount {
+		lineNum = t.lineCount
 	}
-	return l, correctedNum
+	return t.index.Line(lineNum), lineNum
+}
+
+// LineNum returns the 1-based number of the given line of the text.
+func (t *TextImpl) LineNum(l *Line) int {
+	return t.index.LineNum(l)
 }
 
 func (t *TextImpl) SetCurLine(line *Line, lineNum int) {
@@ -905,10 +1148,6 @@ func (t *TextImpl) SetCurLine(line *Line, lineNum int) {
 	t.UpdatePos()
 }
 
-func (t *TextImpl) TopLine() (*Line, int) {
-	return t.topLine, t.topLineNum
-}
-
 func (t *TextImpl) CursorX() int {
 	return t.cursorX
 }
@@ -923,14 +1162,6 @@ func (t *TextImpl) SetCursorX(cursorX int) {
 	t.UpdatePos()
 }
 
-func (t *TextImpl) Resize(w, h int) {
-	t.w, t.h = w, h
-}
-
-func (t *TextImpl) SetFontSize(charW, charH int) {
-	t.charW, t.charH = charW, charH
-}
-
 func (t *TextImpl) SetTabSize(tabSize int) {
 	t.tabSize = tabSize
 }
@@ -939,16 +1170,15 @@ func (t *TextImpl) TabSize() int {
 	return t.tabSize
 }
 
-func (t *TextImpl) ScrollValues() (scrollX, scrollY int) {
-	return t.scrollX, t.scrollY
-}
-
 // SplitLine splits the given line at position x, insereting the new line after the given line.
 func (t *TextImpl) SplitLine(l *Line, x int) {
 	l.Split(x)
+	// The line ending of l moves to the second part, the first one gets the line ending of new lines
+	l.next.NewLineType, l.NewLineType = l.NewLineType, t.newLineType
 	if l == t.last {
 		t.last = l.next
 	}
+	t.index.Insert(t.index.LineNum(l)+1, l.next)
 	t.lineCount++
 }
 
@@ -957,6 +1187,7 @@ func (t *TextImpl) MergeLines(l *Line) {
 	if l.next != nil {
 		length := len(l.chars)
 		l.chars = append(l.chars, l.next.chars...)
+		l.NewLineType = l.next.NewLineType
 
 		if length == 0 {
 			l.runs = l.next.runs
@@ -989,14 +1220,6 @@ func (t *TextImpl) DeleteLine(l *Line) {
 			t.last = l.prev
 		}
 
-		if l == t.topLine {
-			if t.topLine.prev != nil {
-				t.topLine = t.topLine.prev
-			} else {
-				t.topLine = t.first
-			}
-		}
-
 		if l == t.curLine {
 			if l.prev == nil {
 				t.curLine = t.first
@@ -1008,14 +1231,17 @@ func (t *TextImpl) DeleteLine(l *Line) {
 			t.cursorX = 0
 			t.UpdateCursorMem()
 		}
+		t.index.Remove(l)
 		t.lineCount--
-		t.ScrollDelta(0)
+		t.clampScroll()
 		t.MoveToCursor()
 	}
 }
 
 func (t *TextImpl) ColorizeSelection(color int) {
 	if t.selected {
+		t.beginEdit(editColor, t.selection.LineFrom, t.selection.LineTo)
+		defer t.endEdit()
 		sel := t.selection
 		// First line of selection
 		line, lineNum := t.LineByNum(sel.LineFrom)
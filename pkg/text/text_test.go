@@ -0,0 +1,96 @@
+package text
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/patrikaleksandryan/coloride/pkg/charset"
+	"github.com/patrikaleksandryan/coloride/pkg/syntax"
+)
+
+// loadString returns a text loaded from s with the color comments of the language of the file fname.
+func loadString(t *testing.T, fname, s string) *TextImpl {
+	t.Helper()
+	txt := NewText().(*TextImpl)
+	txt.SetColorComment(syntax.ColorCommentFor(fname, ""))
+	err := txt.Load(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txt
+}
+
+func TestColorCommentRoundTrip(t *testing.T) {
+	tests := []struct {
+		fname string
+		src   string
+		chars string // Characters of the first line
+		color int    // Color of the first character
+	}{
+		{"a.go", "x := 1\t\t///1R\nplain\n", "x := 1", 5},
+		{"a.py", "x = 1 ###1g\n", "x = 1", 2},
+		{"a.sh", "echo 1\t###2 3b\n", "echo 1", 0},
+		{"a.sql", "select 1;  --- 7 1b\n", "select 1;", 0},
+		{"a.lua", "x = 1 --- 1G\r\n", "x = 1", 6},
+		{"a.mod", "x := 1; (*/ 1R *)\n", "x := 1;", 5},
+		{"a.mod", "a (*/ b *) c\n", "a (*/ b *) c", 0},
+		{"a.mod", "a (*/ b *) c (*/ 1R *)\n", "a (*/ b *) c", 5},
+		{"a.mod", "a (*/ 1R\n", "a (*/ 1R", 0},
+	}
+	for _, tt := range tests {
+		txt := loadString(t, tt.fname, tt.src)
+		if got := string(txt.first.chars); got != tt.chars {
+			t.Errorf("%s %q: line is %q, want %q", tt.fname, tt.src, got, tt.chars)
+		}
+		if got := txt.first.runs.color; got != tt.color {
+			t.Errorf("%s %q: color is %d, want %d", tt.fname, tt.src, got, tt.color)
+		}
+		if got := writeString(t, txt); got != tt.src {
+			t.Errorf("%s %q: saved as %q", tt.fname, tt.src, got)
+		}
+	}
+}
+
+func TestInvalidUTF8IsKept(t *testing.T) {
+	src := "größe := \"\xff\xfe\" ///3 2R\r\n€ \x80\xc3\n"
+	txt := loadString(t, "a.go", src)
+	if txt.Encoding() != charset.UTF8 {
+		t.Errorf("encoding is %s, want UTF-8", txt.Encoding())
+	}
+	if got := string(txt.first.chars[:5]); got != "größe" {
+		t.Errorf("line starts with %q", got)
+	}
+	if got := writeString(t, txt); got != src {
+		t.Errorf("saved as %q", got)
+	}
+
+	var b bytes.Buffer
+	err := txt.WriteJournal(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered := NewText().(*TextImpl)
+	err = recovered.ReadJournal(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := writeString(t, recovered); got != src {
+		t.Errorf("recovered as %q", got)
+	}
+}
+
+func TestWriteUTF8(t *testing.T) {
+	txt := loadString(t, "a.go", "\xff\xfex\x00 \x00/\x00/\x00/\x001\x00R\x00\n\x00")
+	if txt.Encoding() != charset.UTF16LE {
+		t.Fatalf("encoding is %s, want UTF-16 LE", txt.Encoding())
+	}
+	var b bytes.Buffer
+	err := txt.WriteUTF8(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != "x\n" {
+		t.Errorf("written as %q", got)
+	}
+}
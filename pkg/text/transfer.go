@@ -0,0 +1,80 @@
+package text
+
+import (
+	"github.com/patrikaleksandryan/coloride/pkg/diff"
+)
+
+// Strings returns the characters of all lines of the text.
+func (t *TextImpl) Strings() []string {
+	lines := make([]string, 0, t.lineCount)
+	for line := t.first; line != nil; line = line.next {
+		lines = append(lines, string(line.chars))
+	}
+	return lines
+}
+
+// CopyColorsFrom colors the text the same way as src, which is another revision of the same file.
+// Lines that are equal in both texts get the same runs. Lines that have been changed get the colors
+// of the characters that have been kept.
+func (t *TextImpl) CopyColorsFrom(src *TextImpl) {
+	t.beginEdit(editColor, 1, t.lineCount)
+	defer t.endEdit()
+
+	srcLines := lineSlice(src.first)
+	dstLines := lineSlice(t.first)
+	i, j := 0, 0 // Next unmatched lines of src and t
+	for _, m := range append(diff.Strings(src.Strings(), t.Strings()), diff.Match{A: len(srcLines), B: len(dstLines)}) {
+		// Lines between matches have been changed, pair them in order
+		for ; i != m.A && j != m.B; i, j = i+1, j+1 {
+			transferColors(srcLines[i], dstLines[j])
+		}
+		for j != m.B {
+			dstLines[j].SetCharColors(make([]int, len(dstLines[j].chars)+1))
+			j++
+		}
+		if m.A != len(srcLines) {
+			transferColors(srcLines[m.A], dstLines[m.B])
+		}
+		i, j = m.A+1, m.B+1
+	}
+}
+
+func lineSlice(first *Line) []*Line {
+	var lines []*Line
+	for line := first; line != nil; line = line.next {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// transferColors gives the characters of line to the colors of the matching characters of line from.
+// Characters that do not match get the color of the run they have been inserted into, if any.
+func transferColors(from, to *Line) {
+	to.spaces = append([]rune(nil), from.spaces...)
+	fromColors := from.CharColors()
+	n := len(to.chars)
+	colors := make([]int, n+1)
+	matched := make([]bool, n+1)
+	for _, m := range diff.Runes(from.chars, to.chars) {
+		colors[m.B] = fromColors[m.A]
+		matched[m.B] = true
+	}
+	colors[n] = fromColors[len(from.chars)]
+	matched[n] = true
+
+	prev := 0 // Color of the last matched character
+	for k := 0; k != n; k++ {
+		if matched[k] {
+			prev = colors[k]
+		} else {
+			next := k + 1
+			for !matched[next] {
+				next++
+			}
+			if colors[next] == prev {
+				colors[k] = prev
+			}
+		}
+	}
+	to.SetCharColors(colors)
+}
@@ -0,0 +1,110 @@
+package text
+
+// View is a visible area of a text, i.e. an editor frame. It holds the size of the area and the scroll position.
+// A text may be shown in several views; the active one follows the cursor.
+type View struct {
+	text   *TextImpl
+	reader *Reader
+
+	w, h             int // Size of editor frame in pixels
+	charW, charH     int // Size of character in pixels
+	scrollX, scrollY int // Text scroll relative to frame in pixels, positive
+}
+
+// NewView creates a view of text and makes it active.
+func NewView(text *TextImpl, w, h, charW, charH int) *View {
+	v := &View{
+		text: text,
+	}
+	v.Resize(w, h)
+	v.SetFontSize(charW, charH)
+	v.reader = NewReader(v)
+	text.views = append(text.views, v)
+	text.SetView(v)
+	return v
+}
+
+// RemoveView removes a view that is no longer shown, i.e. when its tab or window is closed.
+// If it was the active view, the text has no active view until SetView is called.
+func (t *TextImpl) RemoveView(view *View) {
+	for i, v := range t.views {
+		if v == view {
+			t.views = append(t.views[:i], t.views[i+1:]...)
+			break
+		}
+	}
+	if t.view == view {
+		t.view = nil
+	}
+}
+
+func (v *View) Text() *TextImpl {
+	return v.text
+}
+
+func (v *View) Reader() *Reader {
+	return v.reader
+}
+
+func (v *View) Resize(w, h int) {
+	v.w, v.h = w, h
+}
+
+func (v *View) SetFontSize(charW, charH int) {
+	v.charW, v.charH = charW, charH
+}
+
+func (v *View) ScrollValues() (scrollX, scrollY int) {
+	return v.scrollX, v.scrollY
+}
+
+// ScrollTo sets the scroll position.
+func (v *View) ScrollTo(scrollX, scrollY int) {
+	v.scrollX, v.scrollY = scrollX, scrollY
+	v.ScrollDelta(0)
+}
+
+func (v *View) ScrollDelta(dy int) {
+	v.scrollY += dy
+
+	max := v.text.lineCount*v.charH - v.h
+	if v.scrollY > max {
+		v.scrollY = max
+	}
+	if v.scrollY < 0 {
+		v.scrollY = 0
+	}
+}
+
+// PageLines returns the number of lines that fit in the view, at least 1.
+func (v *View) PageLines() int {
+	lines := v.h / v.charH
+	if lines == 0 {
+		lines = 1
+	}
+	return lines
+}
+
+// TopLine returns the first line visible in the view and its number.
+func (v *View) TopLine() (*Line, int) {
+	return v.text.LineByNum(v.scrollY/v.charH + 1)
+}
+
+func (v *View) CursorTooLow() bool {
+	return v.text.curLineNum*v.charH > v.scrollY+v.h
+}
+
+func (v *View) CursorTooHigh() bool {
+	return (v.text.curLineNum-1)*v.charH < v.scrollY
+}
+
+// MoveToCursor scrolls the view, so that the cursor is visible.
+func (v *View) MoveToCursor() {
+	if v.CursorTooLow() {
+		y := v.text.curLineNum * v.charH
+		v.scrollY = y - v.h
+	} else if v.CursorTooHigh() {
+		y := (v.text.curLineNum - 1) * v.charH
+		v.scrollY = y
+	}
+}
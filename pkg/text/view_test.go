@@ -0,0 +1,32 @@
+package text
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRemoveView(t *testing.T) {
+	txt := NewText().(*TextImpl)
+	err := txt.Load(strings.NewReader(strings.Repeat("line\n", 100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewView(txt, 800, 160, 8, 16)
+	b := NewView(txt, 800, 160, 8, 16)
+	a.ScrollTo(0, 320)
+	txt.RemoveView(b)
+	if len(txt.views) != 1 || txt.views[0] != a {
+		t.Fatalf("views after removing one: %v", txt.views)
+	}
+	if txt.View() != nil {
+		t.Error("removed view is still active")
+	}
+	b.ScrollTo(0, 320)
+	txt.Clear()
+	if _, y := b.ScrollValues(); y != 320 {
+		t.Error("Clear scrolled a removed view")
+	}
+	if _, y := a.ScrollValues(); y != 0 {
+		t.Error("Clear did not scroll the view of the text")
+	}
+}
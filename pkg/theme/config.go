@@ -0,0 +1,304 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/patrikaleksandryan/coloride/pkg/color"
+	"github.com/patrikaleksandryan/coloride/pkg/palette"
+	"github.com/patrikaleksandryan/coloride/pkg/scanner"
+	"github.com/patrikaleksandryan/coloride/pkg/syntax"
+)
+
+// FileName is the name of a project configuration file. It applies to all files in its directory and below.
+const FileName = ".coloride.json"
+
+// Config is the contents of a configuration file. Colors are written as "#rrggbb" or "#rrggbbaa".
+type Config struct {
+	Palette []ColorConfig     `json:"palette"`
+	Syntax  map[string]string `json:"syntax"` // Symbol class name -> color
+	Chrome  map[string]string `json:"chrome"` // Element name -> color
+
+	LineEnding string `json:"line-ending"` // Line ending of new files: "lf", "crlf" or "cr"
+	Backup     bool   `json:"backup"`      // Whether saving keeps the old contents in "file~"
+}
+
+// ColorConfig changes a palette color with the same name or letter, or adds a new one, which must have a name.
+// Fields that are omitted keep their values.
+type ColorConfig struct {
+	Name       string `json:"name"`
+	Letter     string `json:"letter"`
+	Meaning    string `json:"meaning"`
+	Color      string `json:"color"`      // Text color
+	Background string `json:"background"` // Background color
+	Mark       string `json:"mark"`       // Color of the toolbar button and the legend
+}
+
+var syntaxNames = map[string]int{
+	"none":      syntax.CNone,
+	"comment":   syntax.CComment,
+	"ident":     syntax.CIdent,
+	"keyword":   syntax.CKeyword,
+	"string":    syntax.CString,
+	"number":    syntax.CNumber,
+	"proc-call": syntax.CProcCall,
+	"builtin":   syntax.CBuiltin,
+	"type":      syntax.CType,
+	"operator":  syntax.COperator,
+	"escape":    syntax.CEscape,
+	"local":     syntax.CLocal,
+	"param":     syntax.CParam,
+	"field":     syntax.CField,
+	"type-name": syntax.CTypeName,
+	"package":   syntax.CPackage,
+	"const":     syntax.CConst,
+	"func":      syntax.CFunc,
+}
+
+var lineEndings = map[string]int{
+	"lf":   scanner.LF,
+	"crlf": scanner.CRLF,
+	"cr":   scanner.CR,
+}
+
+func chromeColor(name string) *color.Color {
+	switch name {
+	case "background":
+		return &Chrome.Background
+	case "border-dark":
+		return &Chrome.BorderDark
+	case "border-light":
+		return &Chrome.BorderLight
+	case "selection":
+		return &Chrome.Selection
+	case "selection-bg":
+		return &Chrome.SelectionBg
+	case "selection-colored-bg":
+		return &Chrome.SelectionColoredBg
+	case "found-bg":
+		return &Chrome.FoundBg
+	case "line-number":
+		return &Chrome.LineNumber
+	case "current-line-number":
+		return &Chrome.CurLineNumber
+	}
+	return nil
+}
+
+// Find returns the configuration file that applies to files in dir, or "" if there is none.
+func Find(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		fname := filepath.Join(dir, FileName)
+		if info, err := os.Stat(fname); err == nil && !info.IsDir() {
+			return fname
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load reads and checks a configuration file.
+func Load(fname string) (*Config, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	err = json.Unmarshal(data, c)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	err = c.check()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	return c, nil
+}
+
+// check validates the configuration, so that Apply cannot fail halfway.
+func (c *Config) check() error {
+	for i, cc := range c.Palette {
+		if cc.Name == "" && cc.Letter == "" {
+			return fmt.Errorf("palette color #%d has neither name nor letter", i+1)
+		}
+		if cc.Name != "" && !validName(cc.Name) {
+			return fmt.Errorf("invalid color name %q", cc.Name)
+		}
+		if cc.Letter != "" && !validLetter(cc.Letter) {
+			return fmt.Errorf("invalid color letter %q", cc.Letter)
+		}
+		for _, s := range []string{cc.Color, cc.Background, cc.Mark} {
+			if _, err := parseOptionalColor(s); err != nil {
+				return err
+			}
+		}
+	}
+	for name, s := range c.Syntax {
+		if _, ok := syntaxNames[name]; !ok {
+			return fmt.Errorf("unknown syntax class %q", name)
+		}
+		if _, err := parseColor(s); err != nil {
+			return err
+		}
+	}
+	for name, s := range c.Chrome {
+		if chromeColor(name) == nil {
+			return fmt.Errorf("unknown chrome element %q", name)
+		}
+		if _, err := parseColor(s); err != nil {
+			return err
+		}
+	}
+	if _, ok := lineEndings[c.LineEnding]; c.LineEnding != "" && !ok {
+		return fmt.Errorf("unknown line ending %q", c.LineEnding)
+	}
+	_, err := c.applyPalette(palette.Defaults())
+	return err
+}
+
+// Apply changes the current theme and palette. c must be checked by Load.
+func (c *Config) Apply() {
+	if p, err := c.applyPalette(palette.Current()); err == nil {
+		palette.Set(p)
+	}
+	for name, s := range c.Syntax {
+		syntaxColors[syntaxNames[name]], _ = parseColor(s)
+	}
+	for name, s := range c.Chrome {
+		*chromeColor(name), _ = parseColor(s)
+	}
+	if c.LineEnding != "" {
+		NewLineType = lineEndings[c.LineEnding]
+	}
+	Backup = c.Backup
+}
+
+// applyPalette changes the colors of p as configured and returns the changed palette. It fails if a letter
+// ends up on two colors: runs of one of them would be read back as the other one.
+func (c *Config) applyPalette(p palette.Palette) (palette.Palette, error) {
+	configured := make(map[int]string) // Color number -> name given to it by an earlier palette color
+	for i := range c.Palette {
+		cc := &c.Palette[i]
+		clr := 0
+		if cc.Name != "" {
+			clr = p.ByName(cc.Name)
+		}
+		if clr == 0 && cc.Letter != "" {
+			clr = p.ByLetter(rune(cc.Letter[0]))
+			if name, ok := configured[clr]; ok && cc.Name != "" && name != cc.Name {
+				return nil, fmt.Errorf("colors %q and %q have the same letter %q", name, cc.Name, cc.Letter)
+			}
+		}
+		if clr == 0 {
+			if cc.Name == "" {
+				return nil, fmt.Errorf("new color with letter %q needs a name", cc.Letter)
+			}
+			clr = p.Resolve(cc.Name)
+		}
+		if cc.Name != "" {
+			configured[clr] = cc.Name
+		}
+		applyColor(&p[clr], cc)
+	}
+
+	colors := make(map[rune]string) // Letter -> name of the color
+	for _, e := range p[1:] {
+		if e.Letter == 0 {
+			continue
+		}
+		if name, ok := colors[e.Letter]; ok {
+			return nil, fmt.Errorf("colors %q and %q have the same letter %q", name, e.Name, string(e.Letter))
+		}
+		colors[e.Letter] = e.Name
+	}
+	return p, nil
+}
+
+// applyColor changes the entry of a palette color as configured.
+func applyColor(e *palette.Entry, cc *ColorConfig) {
+	if cc.Name != "" {
+		e.Name = cc.Name
+	}
+	if cc.Letter != "" {
+		e.Letter = rune(cc.Letter[0])
+	}
+	if cc.Meaning != "" {
+		e.Meaning = cc.Meaning
+	}
+	if cc.Color != "" {
+		e.Color, _ = parseColor(cc.Color)
+		e.OverrideColor = true
+	}
+	if cc.Background != "" {
+		e.BgColor, _ = parseColor(cc.Background)
+		e.OverrideBgColor = true
+	}
+	if cc.Mark != "" {
+		e.Mark, _ = parseColor(cc.Mark)
+	} else if cc.Background != "" && e.OverrideColor {
+		e.Mark = e.BgColor
+	}
+}
+
+// LoadFor applies the configuration that applies to files in dir on top of the default theme.
+func LoadFor(dir string) error {
+	Reset()
+	fname := Find(dir)
+	if fname == "" {
+		return nil
+	}
+	c, err := Load(fname)
+	if err != nil {
+		return err
+	}
+	c.Apply()
+	return nil
+}
+
+func validName(name string) bool {
+	for _, ch := range name {
+		if !(ch == '_' || ch == '-' || ch == '.' || '0' <= ch && ch <= '9' ||
+			'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func validLetter(letter string) bool {
+	return len(letter) == 1 && ('a' <= letter[0] && letter[0] <= 'z' || 'A' <= letter[0] && letter[0] <= 'Z')
+}
+
+func parseOptionalColor(s string) (color.Color, error) {
+	if s == "" {
+		return color.Color{}, nil
+	}
+	return parseColor(s)
+}
+
+// parseColor parses "#rrggbb" or "#rrggbbaa".
+func parseColor(s string) (color.Color, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || len(hex) != 6 && len(hex) != 8 {
+		return color.Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.MakeRGBA(uint8(v>>24), uint8(v>>16), uint8(v>>8), uint8(v)), nil
+}
@@ -0,0 +1,65 @@
+package theme
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patrikaleksandryan/coloride/pkg/palette"
+)
+
+// loadConfig loads a configuration file with the given contents.
+func loadConfig(t *testing.T, contents string) (*Config, error) {
+	t.Helper()
+	fname := filepath.Join(t.TempDir(), FileName)
+	err := os.WriteFile(fname, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Load(fname)
+}
+
+func TestLoadRejectsLetterCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		palette string
+		err     string // Part of the error message, "" if the configuration is valid
+	}{
+		{"letter of another default color", `{"name": "red", "letter": "g"}`, `"red" and "green" have the same letter "g"`},
+		{"two new colors", `{"name": "todo", "letter": "t"}, {"name": "fixme", "letter": "t"}`, `"todo" and "fixme" have the same letter "t"`},
+		{"default color renamed by letter", `{"name": "todo", "letter": "r"}`, ""},
+		{"new color renamed by letter", `{"name": "todo", "letter": "t"}, {"name": "todo", "letter": "t", "meaning": "later"}`, ""},
+		{"letters swapped", `{"name": "red", "letter": "x"}, {"name": "green", "letter": "r"}, {"name": "red", "letter": "g"}`, ""},
+		{"meaning by letter", `{"letter": "g", "meaning": "tested"}`, ""},
+		{"unknown letter without name", `{"letter": "t"}`, `new color with letter "t" needs a name`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfig(t, `{"palette": [`+tt.palette+`]}`)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want one containing %s", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestApplyKeepsLettersUnique(t *testing.T) {
+	defer Reset()
+	c, err := loadConfig(t, `{"palette": [{"name": "red", "letter": "x"}, {"name": "green", "letter": "r"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset()
+	c.Apply()
+	if clr := palette.ByLetter('r'); palette.Get(clr).Name != "green" {
+		t.Errorf("letter r belongs to %q", palette.Get(clr).Name)
+	}
+	if clr := palette.ByLetter('x'); palette.Get(clr).Name != "red" {
+		t.Errorf("letter x belongs to %q", palette.Get(clr).Name)
+	}
+}
@@ -0,0 +1,84 @@
+package theme
+
+import (
+	"github.com/patrikaleksandryan/coloride/pkg/color"
+	"github.com/patrikaleksandryan/coloride/pkg/palette"
+	"github.com/patrikaleksandryan/coloride/pkg/scanner"
+	"github.com/patrikaleksandryan/coloride/pkg/syntax"
+)
+
+// ChromeColors are the colors of the editor around and over the text.
+type ChromeColors struct {
+	Background         color.Color // Background of the text area
+	BorderDark         color.Color // Top and left edges of the text area
+	BorderLight        color.Color // Bottom and right edges of the text area
+	Selection          color.Color // Selected text
+	SelectionBg        color.Color // Background of selected text
+	SelectionColoredBg color.Color // Background of selected text that has a color run
+	FoundBg            color.Color // Background of search matches
+	LineNumber         color.Color
+	CurLineNumber      color.Color // Number of the line with the cursor
+}
+
+// Chrome holds the current colors of the editor.
+var Chrome ChromeColors
+
+// NewLineType is the line ending of new files, one of New Line Type constants in scanner.go.
+var NewLineType int
+
+// Backup is whether saving a file keeps its old contents in a backup file.
+var Backup bool
+
+// syntaxColors maps symbol classes to text colors.
+var syntaxColors map[int]color.Color
+
+func init() {
+	Reset()
+}
+
+// Reset restores the default theme and palette.
+func Reset() {
+	Chrome = ChromeColors{
+		Background:         color.Black,
+		BorderDark:         color.MakeColor(113, 92, 72),
+		BorderLight:        color.MakeColor(235, 235, 207),
+		Selection:          color.MakeColor(255, 255, 255),
+		SelectionBg:        color.MakeColor(0, 0, 255),
+		SelectionColoredBg: color.MakeColor(40, 90, 160),
+		FoundBg:            color.MakeColor(130, 90, 20),
+		LineNumber:         color.MakeColor(125, 89, 69),
+		CurLineNumber:      color.MakeColor(235, 235, 203),
+	}
+	NewLineType = scanner.LF
+	Backup = false
+	syntaxColors = map[int]color.Color{
+		syntax.CNone:     color.White,
+		syntax.CComment:  color.MakeColor(120, 120, 120),
+		syntax.CIdent:    color.MakeColor(200, 200, 200),
+		syntax.CKeyword:  color.MakeColor(210, 150, 50),
+		syntax.CString:   color.MakeColor(70, 210, 50),
+		syntax.CNumber:   color.MakeColor(40, 235, 235),
+		syntax.CProcCall: color.MakeColor(200, 180, 100),
+		syntax.CBuiltin:  color.MakeColor(110, 160, 230),
+		syntax.CType:     color.MakeColor(90, 190, 170),
+		syntax.COperator: color.MakeColor(170, 170, 190),
+		syntax.CEscape:   color.MakeColor(190, 240, 130),
+		syntax.CLocal:    color.MakeColor(225, 225, 225),
+		syntax.CParam:    color.MakeColor(230, 190, 150),
+		syntax.CField:    color.MakeColor(150, 200, 240),
+		syntax.CTypeName: color.MakeColor(90, 190, 170),
+		syntax.CPackage:  color.MakeColor(180, 150, 220),
+		syntax.CConst:    color.MakeColor(40, 200, 200),
+		syntax.CFunc:     color.MakeColor(200, 180, 100),
+	}
+	palette.Reset()
+}
+
+// SyntaxColor returns the text color of the given symbol class.
+func SyntaxColor(symbolClass int) color.Color {
+	clr, ok := syntaxColors[symbolClass]
+	if !ok {
+		return syntaxColors[syntax.CNone]
+	}
+	return clr
+}
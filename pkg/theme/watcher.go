@@ -0,0 +1,55 @@
+package theme
+
+import (
+	"os"
+	"time"
+)
+
+// checkInterval is how often the configuration file is checked for changes.
+const checkInterval = time.Second
+
+// Watcher keeps the theme in sync with the configuration file of the directory being edited.
+type Watcher struct {
+	dir       string
+	fname     string
+	modTime   time.Time
+	size      int64
+	lastCheck time.Time
+}
+
+// Check looks for the configuration of files in dir and applies it if the file was changed, created or
+// removed since the last call. When dir is the same, the file is checked at most once per checkInterval.
+// Reports whether the theme was changed. On error the previous theme is kept.
+func (w *Watcher) Check(dir string) (changed bool, err error) {
+	now := time.Now()
+	if dir == w.dir && now.Sub(w.lastCheck) < checkInterval {
+		return false, nil
+	}
+	w.dir = dir
+	w.lastCheck = now
+
+	fname := Find(dir)
+	var modTime time.Time
+	var size int64
+	if fname != "" {
+		if info, err := os.Stat(fname); err == nil {
+			modTime, size = info.ModTime(), info.Size()
+		}
+	}
+	if fname == w.fname && modTime.Equal(w.modTime) && size == w.size {
+		return false, nil
+	}
+	w.fname, w.modTime, w.size = fname, modTime, size
+
+	if fname == "" {
+		Reset()
+		return true, nil
+	}
+	c, err := Load(fname)
+	if err != nil {
+		return false, err
+	}
+	Reset()
+	c.Apply()
+	return true, nil
+}